@@ -1,29 +1,45 @@
 package main
 
 import (
+	"context"
 	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
 	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/httpclient"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
-	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"github.com/MaximInnopolis/ProductCatalog/internal/metrics"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/scheduler"
 	"github.com/MaximInnopolis/ProductCatalog/scripts"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
-var DBPATH = os.Getenv("DB_PATH")
-
 func main() {
+	// Load and validate configuration
+	cfg, _, err := config.Load(os.Args[0], os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
 	// Logger initialization
+	if err := logger.Configure(cfg.Log); err != nil {
+		log.Fatalf("Failed to configure logger: %v", err)
+	}
 	defer func() {
 		if err := logger.Close(); err != nil {
 			log.Fatalf("Failed to close log file: %v", err)
 		}
 	}()
+	go reopenLogsOnHangup()
 
-	// Load env data
-	utils.LoadEnv()
-
-	if err := database.Init(DBPATH); err != nil {
+	if err := database.Init(cfg.Database.Path); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.Close()
@@ -33,18 +49,121 @@ func main() {
 		log.Fatalf("Failed to migrate: %v", err)
 	}
 
-	log.Println("Migration completed successfully")
+	logger.Info("Migration completed successfully")
 
 	// Database records creation (Not necessary)
 	if err := scripts.CreateRecords(database.GetDB()); err != nil {
 		log.Fatalf("Failed to create records: %v", err)
 	}
 
-	log.Println("Records created successfully")
+	logger.Info("Records created successfully")
+
+	// Expose connection pool statistics and catalog size on /metrics
+	if err := metrics.RegisterDatabase(database.GetDB()); err != nil {
+		log.Fatalf("Failed to register database metrics: %v", err)
+	}
+
+	// Set up token signing and make sure there is administrator able to assign roles to other users
+	auth.Configure(cfg.Auth)
+	if cfg.Auth.AdminUsername != "" {
+		if err := models.BootstrapAdmin(context.Background(), database.GetDB(), cfg.Auth.AdminUsername, cfg.Auth.AdminPassword); err != nil {
+			log.Fatalf("Failed to bootstrap admin user: %v", err)
+		}
+	}
+
+	// Configure HTTP client shared by all collector sources
+	httpConfig := httpclient.DefaultConfig()
+	httpConfig.Timeout = cfg.Collector.HTTPTimeout
+	httpConfig.MaxRetries = cfg.Collector.HTTPRetries
+	scripts.SetHTTPClient(httpclient.New(httpConfig))
+
+	// Set up data collection from sources listed in collector configuration
+	sources, err := scripts.LoadSources(cfg.Collector.SourcesFile)
+	if err != nil {
+		log.Fatalf("Failed to load collector sources: %v", err)
+	}
+	collector := scripts.NewCollector(database.GetDB(), sources)
+	api.SetCollector(collector)
+	api.SetDegradeOnCollectorFailure(cfg.Collector.DegradeReadiness)
+
+	// SIGINT and SIGTERM stop server and background jobs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Run collection, cleanup and backup jobs in background
+	jobs := scheduler.New()
+	if err := registerJobs(jobs, cfg, collector); err != nil {
+		log.Fatalf("Failed to register background jobs: %v", err)
+	}
+	jobs.Start(ctx)
+
+	// Serve HTTP requests until shutdown signal, draining in-flight requests
+	server := api.NewServer(cfg.Server, api.NewHandler(cfg.Server))
+	if err := server.ListenAndServe(ctx); err != nil {
+		logger.Error("Server error", "error", err)
+	}
+
+	// Stop background jobs before database is closed
+	stop()
+	collector.Stop()
+	jobs.Wait()
+	logger.Info("Shutdown complete")
+}
+
+// reopenLogsOnHangup reopens log files on every SIGHUP, so that files rotated by external tool such as logrotate
+// are replaced by new ones
+func reopenLogsOnHangup() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := logger.Reopen(); err != nil {
+			logger.Error("Error reopening log files", "error", err)
+			continue
+		}
+		logger.Info("Log files reopened")
+	}
+}
+
+// registerJobs registers background jobs with scheduler: collection of every source,
+// cleanup of old collector run history and, if backup directory is configured, database backup
+func registerJobs(jobs *scheduler.Scheduler, cfg *config.Config, collector *scripts.Collector) error {
+	for _, job := range collector.Jobs() {
+		if err := jobs.Add(job); err != nil {
+			return err
+		}
+	}
 
-	// Start data collection
-	scripts.StartDataCollection()
+	// Cleanup of collector run history
+	cleanupSchedule, err := scheduler.Parse(cfg.Collector.CleanupSchedule)
+	if err != nil {
+		return err
+	}
+	err = jobs.Add(scheduler.Job{
+		Name:     "cleanup collector runs",
+		Schedule: cleanupSchedule,
+		Run: func(ctx context.Context) error {
+			_, err := scripts.CleanupCollectorRuns(ctx, database.GetDB(), cfg.Collector.RunsRetention)
+			return err
+		},
+	})
+	if err != nil {
+		return err
+	}
 
-	// Register HTTP request handlers
-	api.RegisterHandlers()
+	// Database backup
+	if cfg.Backup.Dir == "" {
+		return nil
+	}
+	backupSchedule, err := scheduler.Parse(cfg.Backup.Schedule)
+	if err != nil {
+		return err
+	}
+	return jobs.Add(scheduler.Job{
+		Name:     "backup database",
+		Schedule: backupSchedule,
+		Run: func(ctx context.Context) error {
+			_, err := scripts.BackupDatabase(ctx, database.GetDB(), cfg.Backup.Dir, cfg.Backup.Keep)
+			return err
+		},
+	})
 }
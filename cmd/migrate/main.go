@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"log"
+	"os"
+	"strconv"
+)
+
+const usage = "usage: migrate [-config file] [-db-path path] up|down|status|to N"
+
+func main() {
+	// Load configuration, flags such as -db-path go before command
+	cfg, args, err := config.Load(os.Args[0], os.Args[1:])
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Logger initialization
+	if err := logger.Configure(cfg.Log); err != nil {
+		log.Fatalf("Failed to configure logger: %v", err)
+	}
+	defer func() {
+		if err := logger.Close(); err != nil {
+			log.Fatalf("Failed to close log file: %v", err)
+		}
+	}()
+	if len(args) < 1 {
+		log.Fatal(usage)
+	}
+
+	if err := database.Init(cfg.Database.Path); err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	db := database.GetDB()
+
+	switch command := args[0]; command {
+	case "up":
+		err = scripts.Migrate(db)
+	case "down":
+		err = scripts.MigrateDown(db)
+	case "to":
+		if len(args) != 2 {
+			log.Fatal(usage)
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			log.Fatalf("Invalid migration version %q: %v", args[1], convErr)
+		}
+		err = scripts.MigrateTo(db, version)
+	case "status":
+		err = printStatus()
+	default:
+		log.Fatal(usage)
+	}
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	if version, err := scripts.CurrentVersion(db); err == nil {
+		log.Printf("Current schema version: %d", version)
+	}
+}
+
+// printStatus prints every known migration with its application time, pending or skipped mark
+func printStatus() error {
+	statuses, err := scripts.GetMigrationStatus(database.GetDB())
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		state := "pending"
+		if status.Applied {
+			state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+		} else if status.Skipped {
+			state = "skipped, SQLite lacks required option"
+		}
+		fmt.Printf("%04d  %-30s %s\n", status.Version, status.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"github.com/MaximInnopolis/ProductCatalog/internal/validation"
+	"net/http"
+)
+
+// SetUserRoleHandler handles requests to assign role to user
+// Extracts username from request and parses request body to get new role
+// If successful, writes success message to response; otherwise writes error response
+func SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract username from request
+	username := GetNameFromRequest(r)
+
+	// Parse request body to get role
+	var request setRoleRequest
+	err := validation.DecodeJSON(r, &request)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Update role of user in database
+	err = models.SetUserRole(r.Context(), database.GetDB(), username, request.Role)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Write success message to response
+	utils.WriteJSONResponse(w, http.StatusOK, "Role assigned")
+}
@@ -1,40 +1,36 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
-	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
 	"github.com/MaximInnopolis/ProductCatalog/internal/database"
 	"github.com/MaximInnopolis/ProductCatalog/internal/models"
 	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"github.com/MaximInnopolis/ProductCatalog/internal/validation"
 	"github.com/gorilla/mux"
 	"net/http"
+	"strconv"
 )
 
 // CreateCategoryHandler handles requests to create new category
-// First checks if token is valid
-// Then parses request body to extract category data
+// Parses request body to extract category data
 // If successful, adds category to database and writes success message to response
 // If any errors occur, writes error response
 func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	// Check if token is valid
-	if !auth.RequireValidToken(w, r) {
-		return
-	}
-
 	// Parse request body to get category data
-	var category models.Category
-	err := json.NewDecoder(r.Body).Decode(&category)
+	var request categoryRequest
+	err := validation.DecodeJSON(r, &request)
 	if err != nil {
-		// Write error response with bad request status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusBadRequest)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	// Add category to database
-	_, err = models.AddCategory(database.GetDB(), &category)
+	_, err = models.AddCategory(r.Context(), database.GetDB(), &models.Category{Name: request.Name, Parent: request.Parent})
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -49,10 +45,10 @@ func CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
 func GetCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Get list of categories from database
-	categories, err := models.GetAllCategories(database.GetDB())
+	categories, err := models.GetAllCategories(r.Context(), database.GetDB())
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -61,58 +57,168 @@ func GetCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(categories)
 }
 
+// GetCategoryHandler handles requests to retrieve single category by name
+// Response carries entity tag of category version in ETag header, and is empty with 304 status code
+// if entity tag matches If-None-Match header of request
+// If any errors occur, writes error response
+func GetCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	// Get category from database
+	category, err := models.GetCategory(r.Context(), database.GetDB(), GetNameFromRequest(r))
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Skip category if client already has its current version
+	setEntityTag(w, category.Version)
+	if notModified(w, r, category.Version) {
+		return
+	}
+
+	// Output in JSON format category
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
 // UpdateCategoryHandler handles requests to update existing category
-// Extracts category name from request and checks if token is valid
-// If successful, parses request body to get category data and updates category in database
+// Extracts category name and its expected version from request
+// Then parses request body to get category data and updates category in database,
+// if its version matches entity tag in If-Match header, new entity tag is returned in ETag header
 // If any errors occur during process, writes error response
 func UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	// Extract category name from request
+	// Extract category name and its expected version from request
 	categoryName := GetNameFromRequest(r)
-
-	// Check if token is valid
-	if !auth.RequireValidToken(w, r) {
+	version, err := expectedVersion(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	// Parse request body to get category data
-	var category models.Category
-	err := json.NewDecoder(r.Body).Decode(&category)
+	var request renameCategoryRequest
+	err = validation.DecodeJSON(r, &request)
 	if err != nil {
-		// Write error response with bad request status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusBadRequest)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	// Update category in database
-	err = models.UpdateCategory(database.GetDB(), categoryName, &category)
+	// Update category in database in single transaction
+	category := models.Category{Name: request.Name, Version: version}
+	err = models.WithTx(r.Context(), database.GetDB(), func(tx *sql.Tx) error {
+		return models.UpdateCategory(r.Context(), tx, categoryName, &category)
+	})
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	// Write success message to response
+	setEntityTag(w, category.Version)
 	utils.WriteJSONResponse(w, http.StatusOK, "Category updated")
 }
 
+// PatchCategoryHandler handles requests to change specified category partially
+// Request body is JSON Merge Patch or JSON Patch, according to Content-Type header, applied to document of category
+// with fields name and parent, so that category can be renamed and moved by single request
+// Patch is applied in single transaction and only if its result is valid, and only if category version matches
+// entity tag in If-Match header
+// If successful, writes changed category in JSON format to response with new entity tag in ETag header
+// If any errors occur during process, writes error response
+func PatchCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract category name and its expected version from request
+	categoryName := GetNameFromRequest(r)
+	version, err := expectedVersion(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Parse request body to get patch
+	p, err := readPatch(w, r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Read, patch and update category in single transaction
+	var category *models.Category
+	err = models.WithTx(r.Context(), database.GetDB(), func(tx *sql.Tx) error {
+		current, err := models.GetCategory(r.Context(), tx, categoryName)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckVersion("category", current.Name, current.Version, version); err != nil {
+			return err
+		}
+
+		// Apply patch to document of category and validate result
+		var document categoryRequest
+		if err := applyPatch(p, categoryRequest{Name: current.Name, Parent: current.Parent}, &document); err != nil {
+			return err
+		}
+
+		// Move category first, as new parent is looked up by its current name
+		if document.Parent != current.Parent {
+			if _, err := models.MoveCategory(r.Context(), tx, current.Name, document.Parent, models.AnyVersion); err != nil {
+				return err
+			}
+		}
+		if document.Name != current.Name {
+			renamed := models.Category{Name: document.Name, Version: models.AnyVersion}
+			if err := models.UpdateCategory(r.Context(), tx, current.Name, &renamed); err != nil {
+				return err
+			}
+		}
+		category, err = models.GetCategory(r.Context(), tx, document.Name)
+		return err
+	})
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Output in JSON format changed category
+	setEntityTag(w, category.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(category)
+}
+
 // DeleteCategoryHandler handles requests to delete specified category
-// Extracts category name from request, checks if token is valid,
-// and then deletes category from database.
+// Extracts category name from request and then deletes category from database
+// Query parameter policy selects what happens to products of category: restrict (default),
+// cascade-links or reassign-to, the latter with target category in query parameter target
+// Category is deleted only if its version matches entity tag in If-Match header
 // If any errors occur during process, writes error response
 func DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
-	// Extract category name from request
+	// Extract category name and its expected version from request
 	categoryName := GetNameFromRequest(r)
-
-	// Check if token is valid
-	if !auth.RequireValidToken(w, r) {
+	version, err := expectedVersion(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	// Delete category from database
-	err := models.DeleteCategory(database.GetDB(), categoryName)
+	// Extract deletion policy from request
+	policy := models.DeletePolicy{
+		Mode:       r.URL.Query().Get("policy"),
+		ReassignTo: r.URL.Query().Get("target"),
+	}
+
+	// Delete category from database in single transaction
+	err = models.WithTx(r.Context(), database.GetDB(), func(tx *sql.Tx) error {
+		return models.DeleteCategory(r.Context(), tx, categoryName, policy, version)
+	})
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -120,9 +226,81 @@ func DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSONResponse(w, http.StatusOK, "Category deleted")
 }
 
+// GetCategoryTreeHandler handles requests to retrieve all categories arranged as tree
+// If successful, outputs tree of categories in JSON format
+// If any errors occur, writes error response
+func GetCategoryTreeHandler(w http.ResponseWriter, r *http.Request) {
+	// Get tree of categories from database
+	tree, err := models.GetCategoryTree(r.Context(), database.GetDB())
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Output in JSON format tree of categories
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tree)
+}
+
+// MoveCategoryHandler handles requests to move category under another parent category
+// Extracts category name from request
+// Then parses request body to get name of new parent, empty parent makes category top-level
+// Category is moved only if its version matches entity tag in If-Match header
+// If successful, new entity tag of category is written to ETag header
+// If any errors occur during process, writes error response
+func MoveCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract category name and its expected version from request
+	categoryName := GetNameFromRequest(r)
+	version, err := expectedVersion(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Parse request body to get name of new parent category
+	var move moveCategoryRequest
+	err = validation.DecodeJSON(r, &move)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Move category in database in single transaction
+	err = models.WithTx(r.Context(), database.GetDB(), func(tx *sql.Tx) error {
+		version, err = models.MoveCategory(r.Context(), tx, categoryName, move.Parent, version)
+		return err
+	})
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Write success message to response
+	setEntityTag(w, version)
+	utils.WriteJSONResponse(w, http.StatusOK, "Category moved")
+}
+
 // GetNameFromRequest retrieves category name from URL
 func GetNameFromRequest(r *http.Request) string {
 	vars := mux.Vars(r)
 	categoryName := vars["name"]
 	return categoryName
 }
+
+// parseBoolQuery retrieves boolean query parameter with specified key from URL
+// Missing parameter is treated as false, invalid one is reported as error of kind models.ErrMalformed
+func parseBoolQuery(r *http.Request, key string) (bool, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return false, nil
+	}
+	flag, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, models.MalformedError(models.CodeInvalidParameter, "%s must be true or false", key)
+	}
+	return flag, nil
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"net/http"
+	"strconv"
+)
+
+// collector runs data collection triggered through API, nil until SetCollector is called
+var collector *scripts.Collector
+
+// errCollectorDisabled is returned when collection is requested but no collector is configured
+var errCollectorDisabled = &models.Error{Kind: models.ErrUnavailable, Code: models.CodeCollectorUnavailable, Message: "data collection is not configured"}
+
+// SetCollector sets collector used by collector handlers
+func SetCollector(c *scripts.Collector) {
+	collector = c
+}
+
+// GetCollectorRunsHandler handles requests to retrieve history of collection runs, newest first
+// Query parameter limit sets maximum number of returned runs
+// If any errors occur, writes error response
+func GetCollectorRunsHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse number of runs to return
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil {
+			// Write error response with status code following from error
+			utils.WriteError(w, r, models.MalformedError(models.CodeInvalidParameter, "limit must be integer"))
+			return
+		}
+	}
+
+	// Get runs from database
+	runs, err := models.GetCollectorRuns(r.Context(), database.GetDB(), limit)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Output in JSON format list of runs
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// TriggerCollectionHandler handles requests to start immediate collection from all sources
+// Collection runs in background, request is refused with conflict status code if collection is already running
+func TriggerCollectionHandler(w http.ResponseWriter, r *http.Request) {
+	if collector == nil {
+		// Write error response with service unavailable status code
+		utils.WriteError(w, r, errCollectorDisabled)
+		return
+	}
+
+	// Start collection
+	err := collector.Trigger()
+	if errors.Is(err, scripts.ErrCollectorStopped) {
+		// Write error response with service unavailable status code
+		utils.WriteError(w, r, &models.Error{Kind: models.ErrUnavailable, Code: models.CodeCollectorUnavailable, Message: err.Error(), Err: err})
+		return
+	}
+	if errors.Is(err, scripts.ErrCollectionInProgress) {
+		// Write error response with conflict status code
+		utils.WriteError(w, r, models.ConflictError(models.CodeCollectionInProgress, "%s", err).Wrap(err))
+		return
+	}
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Write success message to response
+	utils.WriteJSONResponse(w, http.StatusAccepted, "Collection started")
+}
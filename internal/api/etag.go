@@ -0,0 +1,75 @@
+package api
+
+import (
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// noVersion is version that never matches stored one, it is used for entity tags not issued by server
+const noVersion int64 = -1
+
+// errVersionRequired is returned when request changing resource has no If-Match header
+var errVersionRequired = &models.Error{
+	Kind:    models.ErrPreconditionRequired,
+	Code:    models.CodeVersionRequired,
+	Message: "If-Match header with entity tag of resource is required",
+}
+
+// entityTag returns strong entity tag of resource with specified version
+func entityTag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// setEntityTag sets ETag header of response to entity tag of resource with specified version
+func setEntityTag(w http.ResponseWriter, version int64) {
+	w.Header().Set("ETag", entityTag(version))
+}
+
+// notModified checks If-None-Match header of request against resource with specified version
+// If one of listed entity tags matches, writes 304 response and returns true
+// Entity tags are compared weakly, so W/ prefix is ignored
+func notModified(w http.ResponseWriter, r *http.Request, version int64) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := entityTag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// expectedVersion reads version of resource expected by request from its If-Match header
+// "*" matches any version and results in models.AnyVersion, weak and unknown entity tags never match
+// Returns error of kind models.ErrPreconditionRequired if header is missing
+func expectedVersion(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, errVersionRequired
+	}
+
+	version := noVersion
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return models.AnyVersion, nil
+		}
+		parsed, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+		if err != nil || parsed <= 0 || tag != entityTag(parsed) {
+			continue
+		}
+		if version != noVersion && version != parsed {
+			return 0, &models.Error{Kind: models.ErrMalformed, Code: models.CodeInvalidVersion,
+				Message: "If-Match header must list single entity tag"}
+		}
+		version = parsed
+	}
+	return version, nil
+}
@@ -2,36 +2,78 @@ package api
 
 import (
 	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
-	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/internal/metrics"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
 	"github.com/gorilla/mux"
-	"log"
 	"net/http"
 )
 
-// RegisterHandlers registers HTTP request handlers
-func RegisterHandlers() {
+// NewRouter creates router with all HTTP request handlers registered
+// Router does not serve requests by itself, it is wrapped in middleware chain by NewHandler or mounted in tests
+func NewRouter() *mux.Router {
 	router := mux.NewRouter()
+	router.Use(requestLogger)
 
+	// Unknown routes are reported as problems like all other errors
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteError(w, r, models.NotFoundError(models.CodeRouteNotFound, "no route matches %s", r.URL.Path))
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.WriteError(w, r, &models.Error{
+			Kind:    models.ErrMethodNotAllowed,
+			Code:    models.CodeMethodNotAllowed,
+			Message: "method " + r.Method + " is not allowed",
+		})
+	})
+
+	// Each route declares minimal role it requires, routes without role are public
 	//CRUD category
 	categoriesRouter := router.PathPrefix("/categories").Subrouter()
-	categoriesRouter.HandleFunc("/new", CreateCategoryHandler).Methods("POST")      // CREATE
-	categoriesRouter.HandleFunc("/list", GetCategoriesHandler).Methods("GET")       // READ
-	categoriesRouter.HandleFunc("/{name}", UpdateCategoryHandler).Methods("PUT")    // UPDATE
-	categoriesRouter.HandleFunc("/{name}", DeleteCategoryHandler).Methods("DELETE") // DELETE
+	categoriesRouter.HandleFunc("/new", auth.RequireRole(models.RoleEditor, CreateCategoryHandler)).Methods("POST")        // CREATE
+	categoriesRouter.HandleFunc("/list", GetCategoriesHandler).Methods("GET")                                              // READ
+	categoriesRouter.HandleFunc("/tree", GetCategoryTreeHandler).Methods("GET")                                            // READ tree
+	categoriesRouter.HandleFunc("/{name}/parent", auth.RequireRole(models.RoleEditor, MoveCategoryHandler)).Methods("PUT") // MOVE
+	categoriesRouter.HandleFunc("/{name}/products", GetProductsByCategoryHandler).Methods("GET")                           // READ products
+	categoriesRouter.HandleFunc("/{name}", GetCategoryHandler).Methods("GET")                                              // READ one
+	categoriesRouter.HandleFunc("/{name}", auth.RequireRole(models.RoleEditor, UpdateCategoryHandler)).Methods("PUT")      // UPDATE
+	categoriesRouter.HandleFunc("/{name}", auth.RequireRole(models.RoleEditor, PatchCategoryHandler)).Methods("PATCH")     // PATCH
+	categoriesRouter.HandleFunc("/{name}", auth.RequireRole(models.RoleAdmin, DeleteCategoryHandler)).Methods("DELETE")    // DELETE
 
 	//CRUD product
 	productsRouter := router.PathPrefix("/products").Subrouter()
-	productsRouter.HandleFunc("/new", CreateProductHandler).Methods("POST")           // CREATE
-	productsRouter.HandleFunc("/{name}", GetProductsByCategoryHandler).Methods("GET") // READ
-	productsRouter.HandleFunc("", UpdateProductHandler).Methods("PUT")                // UPDATE
-	productsRouter.HandleFunc("/{name}", DeleteProductHandler).Methods("DELETE")      // DELETE
+	productsRouter.HandleFunc("/new", auth.RequireRole(models.RoleEditor, CreateProductHandler)).Methods("POST")              // CREATE
+	productsRouter.HandleFunc("", GetProductsHandler).Methods("GET")                                                          // READ all
+	productsRouter.HandleFunc("/{id:[0-9]+}", GetProductHandler).Methods("GET")                                               // READ one
+	productsRouter.HandleFunc("", auth.RequireRole(models.RoleEditor, UpdateProductHandler)).Methods("PUT")                   // UPDATE by name
+	productsRouter.HandleFunc("/{id:[0-9]+}", auth.RequireRole(models.RoleEditor, UpdateProductHandler)).Methods("PUT")       // UPDATE by ID
+	productsRouter.HandleFunc("/{id:[0-9]+}", auth.RequireRole(models.RoleEditor, PatchProductHandler)).Methods("PATCH")      // PATCH
+	productsRouter.HandleFunc("/{id:[0-9]+}", auth.RequireRole(models.RoleEditor, DeleteProductHandler)).Methods("DELETE")    // DELETE by ID
+	productsRouter.HandleFunc("/by-name/{name}", auth.RequireRole(models.RoleEditor, DeleteProductHandler)).Methods("DELETE") // DELETE by name
+
+	// Admin router
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.HandleFunc("/users/{name}/role", auth.RequireRole(models.RoleAdmin, SetUserRoleHandler)).Methods("PUT")
+	adminRouter.HandleFunc("/collector/runs", auth.RequireRole(models.RoleAdmin, GetCollectorRunsHandler)).Methods("GET")
+	adminRouter.HandleFunc("/collector/run", auth.RequireRole(models.RoleAdmin, TriggerCollectionHandler)).Methods("POST")
+
+	// Service health including state of data collection
+	router.HandleFunc("/health", HealthHandler).Methods("GET")
+
+	// Liveness and readiness probes of orchestrator
+	router.HandleFunc("/healthz", LivenessHandler).Methods("GET")
+	router.HandleFunc("/readyz", ReadinessHandler).Methods("GET")
+
+	// Prometheus metrics of requests, database, collector and logins
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Full-text product search
+	router.HandleFunc("/search", SearchHandler).Methods("GET")
 
 	// Auth router
 	authRouter := router.PathPrefix("/auth").Subrouter()
 	authRouter.HandleFunc("/register", auth.RegisterUserHandler).Methods("POST")
 	authRouter.HandleFunc("/login", auth.LoginUserHandler).Methods("POST")
 
-	// HTTP server start
-	logger.Println("Server started on port 8080")
-	log.Fatal(http.ListenAndServe(":8080", router))
+	return router
 }
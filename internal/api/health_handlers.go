@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"github.com/MaximInnopolis/ProductCatalog/internal/version"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"net/http"
+	"time"
+)
+
+// HealthResponse is state of service reported by health endpoint
+type HealthResponse struct {
+	Status    string          `json:"status"`
+	Collector CollectorHealth `json:"collector"`
+}
+
+// CollectorHealth is state of data collection reported by health endpoint
+type CollectorHealth struct {
+	Running bool                 `json:"running"`
+	LastRun *models.CollectorRun `json:"last_run"`
+}
+
+// HealthHandler handles requests to retrieve state of service including last collection run
+// If any errors occur, writes error response
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	// Get last collection run from database
+	lastRun, err := models.GetLastCollectorRun(r.Context(), database.GetDB())
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	response := HealthResponse{
+		Status: "ok",
+		Collector: CollectorHealth{
+			Running: collector != nil && collector.Running(),
+			LastRun: lastRun,
+		},
+	}
+
+	// Output in JSON format state of service
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Statuses of service and its components reported by probe endpoints
+const (
+	// StatusOK means component works normally
+	StatusOK = "ok"
+	// StatusDegraded means component works, but something needs attention, service still accepts requests
+	StatusDegraded = "degraded"
+	// StatusDown means component does not work and service cannot serve requests
+	StatusDown = "down"
+)
+
+// readinessTimeout limits time of checks made by readiness probe
+const readinessTimeout = 2 * time.Second
+
+// degradeOnCollectorFailure makes readiness probe report degraded state when last collection run failed
+var degradeOnCollectorFailure bool
+
+// SetDegradeOnCollectorFailure sets whether readiness probe reports degraded state when last collection run failed
+func SetDegradeOnCollectorFailure(degrade bool) {
+	degradeOnCollectorFailure = degrade
+}
+
+// ProbeResponse is state of service reported by liveness and readiness probes
+type ProbeResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+	Build      version.Info               `json:"build"`
+}
+
+// ComponentStatus is state of single component checked by readiness probe
+type ComponentStatus struct {
+	Status  string                 `json:"status"`
+	Error   string                 `json:"error,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// LivenessHandler handles liveness probe, which succeeds whenever server is able to respond
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeProbeResponse(w, ProbeResponse{Status: StatusOK, Build: version.Get()})
+}
+
+// ReadinessHandler handles readiness probe, which checks that database responds and its schema is up to date
+// Failure of last collection run is reported as degraded state if enabled by SetDegradeOnCollectorFailure
+// Responds with service unavailable status code if any component is down
+func ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	db := database.GetDB()
+	components := map[string]ComponentStatus{
+		"database":   checkDatabase(ctx, db),
+		"migrations": checkMigrations(ctx, db),
+	}
+	if degradeOnCollectorFailure {
+		components["collector"] = checkCollector(ctx, db)
+	}
+
+	response := ProbeResponse{Status: StatusOK, Components: components, Build: version.Get()}
+	for name, component := range components {
+		if component.Status != StatusOK {
+			logger.FromContext(ctx).Warn("Readiness check failed", "component", name, "status", component.Status, "error", component.Error)
+		}
+		switch {
+		case component.Status == StatusDown:
+			response.Status = StatusDown
+		case component.Status == StatusDegraded && response.Status == StatusOK:
+			response.Status = StatusDegraded
+		}
+	}
+	writeProbeResponse(w, response)
+}
+
+// checkDatabase checks that database responds
+func checkDatabase(ctx context.Context, db *sql.DB) ComponentStatus {
+	if db == nil {
+		return ComponentStatus{Status: StatusDown, Error: "database is not initialized"}
+	}
+	if err := db.PingContext(ctx); err != nil {
+		return ComponentStatus{Status: StatusDown, Error: err.Error()}
+	}
+	return ComponentStatus{Status: StatusOK}
+}
+
+// checkMigrations checks that all known migrations are applied to database
+// Migrations skipped because SQLite lacks required option are not pending
+func checkMigrations(ctx context.Context, db *sql.DB) ComponentStatus {
+	if db == nil {
+		return ComponentStatus{Status: StatusDown, Error: "database is not initialized"}
+	}
+	current, pending, err := scripts.PendingMigrations(ctx, db)
+	if err != nil {
+		return ComponentStatus{Status: StatusDown, Error: err.Error()}
+	}
+
+	component := ComponentStatus{Status: StatusOK, Details: map[string]interface{}{"version": current, "pending": len(pending)}}
+	if len(pending) > 0 {
+		component.Status = StatusDown
+		component.Error = "database schema is not up to date"
+	}
+	return component
+}
+
+// checkCollector checks that last collection run did not fail
+func checkCollector(ctx context.Context, db *sql.DB) ComponentStatus {
+	if db == nil {
+		return ComponentStatus{Status: StatusDown, Error: "database is not initialized"}
+	}
+	lastRun, err := models.GetLastCollectorRun(ctx, db)
+	if err != nil {
+		return ComponentStatus{Status: StatusDegraded, Error: err.Error()}
+	}
+	if lastRun == nil {
+		return ComponentStatus{Status: StatusOK}
+	}
+
+	component := ComponentStatus{Status: StatusOK, Details: map[string]interface{}{"source": lastRun.Source, "last_run": lastRun.Status}}
+	if lastRun.Status == models.RunFailed {
+		component.Status = StatusDegraded
+		component.Error = lastRun.Error
+	}
+	return component
+}
+
+// writeProbeResponse writes state of service, with service unavailable status code if service is down
+func writeProbeResponse(w http.ResponseWriter, response ProbeResponse) {
+	status := http.StatusOK
+	if response.Status == StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	// Probe results must not be cached by proxies
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
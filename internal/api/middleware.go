@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/internal/metrics"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+)
+
+// RequestIDHeader is header carrying ID of request, taken from client or generated by server
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern matches request IDs accepted from clients, other IDs are replaced to keep logs clean
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Middleware wraps handler with additional behavior
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps handler with middlewares, first middleware becomes outermost one
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// NewHandler creates handler serving all routes of NewRouter through middleware chain:
+// request ID, access log in format configured by server configuration, request metrics, panic recovery
+// and limit of request body size
+func NewHandler(config config.ServerConfig) http.Handler {
+	return Chain(NewRouter(), WithRequestID, AccessLog(config.AccessLog), Metrics, Recover, LimitBody(config.MaxBodyBytes))
+}
+
+// requestInfo holds data about request discovered by inner handlers and reported by outer middlewares
+type requestInfo struct {
+	id    string
+	route string
+}
+
+// requestInfoKey is key of requestInfo in request context
+type requestInfoKey struct{}
+
+// RequestID returns ID of request served with specified context, or empty string if request has no ID
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(requestInfoKey{}).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// WithRequestID takes ID of request from X-Request-ID header or generates new one,
+// returns it in response header and adds it to logger passed to handler in request context
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestInfoKey{}, &requestInfo{id: id})
+		ctx = logger.With(ctx, "request_id", id, "method", r.Method)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestLogger adds route matched by router to logger passed to handler in request context
+// Handlers and models log through logger.FromContext, so their records can be matched with request
+// Name of authenticated user is added by auth.RequireRole
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := mux.CurrentRoute(r); route != nil {
+			if template, err := route.GetPathTemplate(); err == nil {
+				if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+					info.route = template
+				}
+				r = r.WithContext(logger.With(r.Context(), "route", template))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AccessLog logs every served request with its status, response size and latency in specified format
+// Combined format follows Apache combined log format, JSON format logs same data as record attributes
+func AccessLog(format string) Middleware {
+	return func(next http.Handler) http.Handler {
+		if format == config.AccessLogOff {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			recorder := recordResponse(w)
+			next.ServeHTTP(recorder, r)
+			duration := time.Since(start)
+
+			route := ""
+			if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+				route = info.route
+			}
+
+			log := logger.FromContext(r.Context())
+			if format == config.AccessLogJSON {
+				log.Info("Request served",
+					"path", r.URL.RequestURI(),
+					"route", route,
+					"status", recorder.status,
+					"bytes", recorder.bytes,
+					"duration", duration,
+					"remote_addr", remoteHost(r),
+					"user_agent", r.UserAgent(),
+					"referer", r.Referer(),
+				)
+				return
+			}
+			log.Info(fmt.Sprintf(`%s - - [%s] "%s %s %s" %d %d "%s" "%s"`,
+				remoteHost(r), start.Format("02/Jan/2006:15:04:05 -0700"), r.Method, r.URL.RequestURI(), r.Proto,
+				recorder.status, recorder.bytes, orDash(r.Referer()), orDash(r.UserAgent())),
+				"duration", duration)
+		})
+	}
+}
+
+// Metrics counts served requests and measures their latency by method, matched route and status code
+// Route template is used instead of path, so that IDs in paths do not create new series
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := recordResponse(w)
+		next.ServeHTTP(recorder, r)
+
+		route := ""
+		if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+			route = info.route
+		}
+		metrics.ObserveRequest(r.Method, route, recorder.status, time.Since(start))
+	})
+}
+
+// Recover turns panic in handler into internal server error response and logs panic with stack trace
+// Panic with http.ErrAbortHandler is propagated, as it is used to abort response on purpose
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := recordResponse(w)
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			logger.FromContext(r.Context()).Error("Handler panicked", "panic", recovered, "stack", string(debug.Stack()))
+			// Response cannot be replaced once its header is sent
+			if !recorder.wroteHeader {
+				utils.WriteError(recorder, r, &models.Error{Kind: models.ErrInternal, Code: models.CodeInternalError,
+					Message: "internal server error"})
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// LimitBody rejects requests with body larger than maxBytes bytes with 413 status code
+// Requests declaring larger Content-Length are rejected at once, other bodies fail when handler reads past limit
+func LimitBody(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				utils.WriteError(w, r, &models.Error{Kind: models.ErrTooLarge, Code: models.CodeBodyTooLarge,
+					Message: fmt.Sprintf("request body must not exceed %d bytes", maxBytes)})
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// responseRecorder remembers status code and size of response written through it
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// recordResponse wraps response writer in responseRecorder, unless it is responseRecorder already
+func recordResponse(w http.ResponseWriter) *responseRecorder {
+	if recorder, ok := w.(*responseRecorder); ok {
+		return recorder
+	}
+	return &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// WriteHeader records status code and sends it to client
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records size of written data and sends data to client
+func (r *responseRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns underlying response writer, so that http.ResponseController can reach it
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// newRequestID returns random identifier of request
+func newRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
+
+// remoteHost returns address of client without port
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// orDash returns value or "-" if value is empty, as empty fields are written in combined log format
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
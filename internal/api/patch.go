@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/patch"
+	"github.com/MaximInnopolis/ProductCatalog/internal/validation"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// acceptedPatchTypes lists media types of patches accepted by PATCH requests
+var acceptedPatchTypes = []string{patch.MergePatchType, patch.JSONPatchType}
+
+// readPatch decodes body of PATCH request according to its Content-Type header
+// Request with other content type is rejected with error of kind models.ErrUnsupportedMediaType,
+// in this case accepted media types are listed in Accept-Patch header of response
+func readPatch(w http.ResponseWriter, r *http.Request) (patch.Patch, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != patch.MergePatchType && mediaType != patch.JSONPatchType {
+		w.Header().Set("Accept-Patch", strings.Join(acceptedPatchTypes, ", "))
+		return nil, &models.Error{
+			Kind:    models.ErrUnsupportedMediaType,
+			Code:    models.CodeUnsupportedMediaType,
+			Message: "Content-Type of patch must be one of " + strings.Join(acceptedPatchTypes, ", "),
+		}
+	}
+
+	// Read single JSON value, body size and trailing data are checked as for all requests
+	var body json.RawMessage
+	if err := validation.Decode(r, &body); err != nil {
+		return nil, err
+	}
+	return patch.Decode(mediaType, body)
+}
+
+// applyPatch applies patch to document and decodes changed document into struct pointed to by patched
+// Changed document is decoded strictly and validated like request body, so patch cannot set unknown fields
+// or invalid values; nothing is changed in database until whole patch is applied and its result is valid
+func applyPatch(p patch.Patch, document, patched interface{}) error {
+	value, err := patch.Value(document)
+	if err != nil {
+		return err
+	}
+	value, err = p.Apply(value)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	err = validation.Unmarshal(data, patched)
+	// Patch itself is well-formed, it is document changed by patch that cannot be decoded
+	var malformed *models.Error
+	if errors.As(err, &malformed) && errors.Is(malformed, models.ErrMalformed) {
+		return (&models.Error{
+			Kind:    models.ErrValidation,
+			Code:    models.CodeInvalidPatch,
+			Message: "patch produces invalid document: " + malformed.Message,
+		}).Wrap(err)
+	}
+	return err
+}
@@ -1,45 +1,40 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
-	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
 	"github.com/MaximInnopolis/ProductCatalog/internal/database"
 	"github.com/MaximInnopolis/ProductCatalog/internal/models"
 	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"github.com/MaximInnopolis/ProductCatalog/internal/validation"
+	"github.com/gorilla/mux"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
-// requestData stores data received in request body in CreateProductHandler and UpdateProductHandler
-var requestData struct {
-	Name       string            `json:"Name"`
-	Categories []models.Category `json:"Categories"`
-}
-
 // CreateProductHandler handles requests to create new product
-// First checks if token provided in request is valid
-// Then parses request body to extract product data and creates new product in database
+// Parses request body to extract product data and creates new product in database
 // If successful, writes success message to response; otherwise writes error response
 func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
-	// Check if token is valid
-	if !auth.RequireValidToken(w, r) {
-		return
-	}
-
 	// Parse request body to get product data
-	err := json.NewDecoder(r.Body).Decode(&requestData)
+	var request productRequest
+	err := validation.DecodeJSON(r, &request)
 	if err != nil {
-		// Write error response with bad request status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusBadRequest)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	// Create product struct and insert product name from request data
-	product := models.Product{Name: requestData.Name}
-	// Add product to database
-	err = models.AddProduct(database.GetDB(), &product, requestData.Categories)
+	// Create product struct and insert product attributes from request data
+	product := request.product()
+	// Add product to database in single transaction
+	err = models.WithTx(r.Context(), database.GetDB(), func(tx *sql.Tx) error {
+		return models.AddProduct(r.Context(), tx, &product, request.categories())
+	})
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 	// Write success message to response
@@ -47,55 +42,159 @@ func CreateProductHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // UpdateProductHandler handles requests to update existing product
-// First checks if token provided in request is valid
-// Then parses request body to extract product data and updates corresponding product in database
+// Parses request body to extract product data and updates corresponding product in database
+// Product is found by ID in URL, so that it can be renamed, or by name in request body if URL has no ID
+// Product is updated only if its version matches entity tag in If-Match header, new entity tag is returned in ETag header
 // If successful, writes success message to response; otherwise writes error response
 func UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
-	// Check if token is valid
-	if !auth.RequireValidToken(w, r) {
+	// Extract product ID, if any, and expected version of product from request
+	var productID int64
+	_, byID := mux.Vars(r)["id"]
+	if byID {
+		var err error
+		if productID, err = productIDFromRequest(r); err != nil {
+			// Write error response with status code following from error
+			utils.WriteError(w, r, err)
+			return
+		}
+	}
+	version, err := expectedVersion(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	// Parse request body to get product data
-	err := json.NewDecoder(r.Body).Decode(&requestData)
+	var request productRequest
+	err = validation.DecodeJSON(r, &request)
 	if err != nil {
-		// Write error response with bad request status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusBadRequest)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	// Create product struct and insert product name from request data
-	product := models.Product{Name: requestData.Name}
-	// Update product in database
-	err = models.UpdateProduct(database.GetDB(), &product, requestData.Categories)
+	// Create product struct and insert product attributes from request data
+	product := request.product()
+	product.ID = int(productID)
+	product.Version = version
+	// Update product in database in single transaction
+	err = models.WithTx(r.Context(), database.GetDB(), func(tx *sql.Tx) error {
+		return models.UpdateProduct(r.Context(), tx, &product, request.categories())
+	})
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 	// Write success message to response
+	setEntityTag(w, product.Version)
 	utils.WriteJSONResponse(w, http.StatusOK, "Product updated")
 }
 
+// PatchProductHandler handles requests to change product with specified ID partially
+// Request body is JSON Merge Patch or JSON Patch, according to Content-Type header, applied to document of product
+// with fields name, price, currency, description, sku and categories, where categories is object mapping
+// category names to true, so that single category can be added or removed
+// Patch is applied in single transaction and only if its result is valid, and only if product version matches
+// entity tag in If-Match header
+// If successful, writes changed product in JSON format to response with new entity tag in ETag header;
+// otherwise writes error response
+func PatchProductHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract product ID and its expected version from request
+	productID, err := productIDFromRequest(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+	version, err := expectedVersion(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Parse request body to get patch
+	p, err := readPatch(w, r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Read, patch and update product in single transaction
+	var details *models.ProductDetails
+	err = models.WithTx(r.Context(), database.GetDB(), func(tx *sql.Tx) error {
+		current, err := models.GetProduct(r.Context(), tx, productID)
+		if err != nil {
+			return err
+		}
+		if err := models.CheckVersion("product", current.Name, current.Version, version); err != nil {
+			return err
+		}
+
+		// Apply patch to document of product and validate result
+		var document productDocument
+		if err := applyPatch(p, newProductDocument(current), &document); err != nil {
+			return err
+		}
+
+		product := document.product()
+		product.ID = current.ID
+		product.Version = current.Version
+		if err := models.UpdateProduct(r.Context(), tx, &product, document.categories()); err != nil {
+			return err
+		}
+		details, err = models.GetProduct(r.Context(), tx, productID)
+		return err
+	})
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Output in JSON format changed product
+	setEntityTag(w, details.Version)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(details)
+}
+
 // DeleteProductHandler handles requests to delete existing product
-// First extracts product name from request
-// Then checks if token provided in request is valid
-// If token is valid, proceeds to delete product from database
+// First extracts product ID or name and its expected version from request
+// Then deletes product from database, if its version matches entity tag in If-Match header
 // If successful, writes success message to response; otherwise writes error response
 func DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
-	// Extract product name from request
+	// Extract product ID or name and its expected version from request
+	var productID int64
+	_, byID := mux.Vars(r)["id"]
+	if byID {
+		var err error
+		if productID, err = productIDFromRequest(r); err != nil {
+			// Write error response with status code following from error
+			utils.WriteError(w, r, err)
+			return
+		}
+	}
 	productName := GetNameFromRequest(r)
-
-	// Check if token is valid
-	if !auth.RequireValidToken(w, r) {
+	version, err := expectedVersion(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	// Delete product from database
-	err := models.DeleteProduct(database.GetDB(), productName)
+	// Delete product from database in single transaction
+	err = models.WithTx(r.Context(), database.GetDB(), func(tx *sql.Tx) error {
+		if byID {
+			return models.DeleteProductByID(r.Context(), tx, productID, version)
+		}
+		return models.DeleteProduct(r.Context(), tx, productName, version)
+	})
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -106,20 +205,136 @@ func DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
 // GetProductsByCategoryHandler handles requests to retrieve list of products by category
 // First extracts category name from request
 // Then retrieves list of products associated with specified category name from database
+// With query parameter recursive=true, products of all subcategories are included
 // If successful, writes list of products in JSON format to response; otherwise writes error response
 func GetProductsByCategoryHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract category name from request
 	categoryName := GetNameFromRequest(r)
 
+	// Check whether products of subcategories are requested
+	recursive, err := parseBoolQuery(r, "recursive")
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
 	// Get list of products by category name from database
-	products, err := models.GetProductsByCategory(database.GetDB(), categoryName)
+	products, err := models.GetProductsByCategory(r.Context(), database.GetDB(), categoryName, recursive)
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	// Output in JSON format list of categories
+	// Output in JSON format list of products
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(products)
 }
+
+// GetProductsHandler handles requests to retrieve page of all products
+// Reads pagination and sorting options from query parameters limit, offset, sort and order
+// If successful, writes page of products with total number of products in JSON format to response;
+// otherwise writes error response
+func GetProductsHandler(w http.ResponseWriter, r *http.Request) {
+	// Parse pagination and sorting options from query parameters
+	options, err := parseProductListOptions(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Get page of products from database
+	products, total, err := models.GetProducts(r.Context(), database.GetDB(), &options)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Output in JSON format page of products
+	response := models.ProductListResponse{
+		Products: products,
+		Total:    total,
+		Limit:    options.Limit,
+		Offset:   options.Offset,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetProductHandler handles requests to retrieve single product by ID
+// Extracts product ID from request and retrieves product together with its categories from database
+// Response carries entity tag of product version in ETag header, and is empty with 304 status code
+// if entity tag matches If-None-Match header of request
+// If successful, writes product in JSON format to response; otherwise writes error response
+func GetProductHandler(w http.ResponseWriter, r *http.Request) {
+	// Extract product ID from request
+	productID, err := productIDFromRequest(r)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Get product from database
+	product, err := models.GetProduct(r.Context(), database.GetDB(), productID)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Skip product if client already has its current version
+	setEntityTag(w, product.Version)
+	if notModified(w, r, product.Version) {
+		return
+	}
+
+	// Output in JSON format product
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(product)
+}
+
+// productIDFromRequest retrieves product ID from URL
+// Route accepts only digits, so zero ID or ID too large to parse cannot belong to any product and is reported as not found
+func productIDFromRequest(r *http.Request) (int64, error) {
+	value := mux.Vars(r)["id"]
+	productID, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || productID == 0 {
+		return 0, models.NotFoundError(models.CodeProductNotFound, "product %s not found", value)
+	}
+	return productID, nil
+}
+
+// parseProductListOptions reads pagination and sorting options from query parameters of request
+// Parameter order accepts asc or desc, sort accepts name of product field
+func parseProductListOptions(r *http.Request) (models.ProductListOptions, error) {
+	query := r.URL.Query()
+	options := models.ProductListOptions{Sort: query.Get("sort")}
+
+	// Parse numeric pagination parameters
+	var err error
+	if value := query.Get("limit"); value != "" {
+		if options.Limit, err = strconv.Atoi(value); err != nil {
+			return options, models.MalformedError(models.CodeInvalidParameter, "limit must be integer")
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		if options.Offset, err = strconv.Atoi(value); err != nil {
+			return options, models.MalformedError(models.CodeInvalidParameter, "offset must be integer")
+		}
+	}
+
+	// Parse sort order
+	switch strings.ToLower(query.Get("order")) {
+	case "", "asc":
+	case "desc":
+		options.Desc = true
+	default:
+		return options, models.MalformedError(models.CodeInvalidParameter, "order must be asc or desc")
+	}
+
+	return options, nil
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"sort"
+)
+
+// Request bodies accepted by handlers, they are decoded and checked with validation.DecodeJSON
+// Every request is decoded into its own value, handlers must not keep request data in package variables,
+// as requests are served concurrently
+// Rules are described by validate tags, see validation.Validate
+
+// categoryRequest is body of request to create category
+// It is also document changed by PATCH requests to category, see PatchCategoryHandler
+type categoryRequest struct {
+	Name   string `json:"name" validate:"trim,nfc,required,max=100,charset=name"`
+	Parent string `json:"parent" validate:"trim,nfc,max=100,charset=name"`
+}
+
+// renameCategoryRequest is body of request to update category
+type renameCategoryRequest struct {
+	Name string `json:"name" validate:"trim,nfc,required,max=100,charset=name"`
+}
+
+// moveCategoryRequest is body of request to move category, empty parent makes category top-level
+type moveCategoryRequest struct {
+	Parent string `json:"parent" validate:"trim,nfc,max=100,charset=name"`
+}
+
+// productCategory references category of product by name
+type productCategory struct {
+	Name string `json:"name" validate:"trim,nfc,required,max=100,charset=name"`
+}
+
+// productRequest is body of request to create or update product
+type productRequest struct {
+	Name        string            `json:"Name" validate:"trim,nfc,required,max=200,charset=name"`
+	Price       int64             `json:"Price" validate:"min=0"`
+	Currency    string            `json:"Currency" validate:"trim,min=3,max=3,charset=upper"`
+	Description string            `json:"Description" validate:"trim,nfc,max=5000,charset=text"`
+	SKU         string            `json:"SKU" validate:"trim,max=64,charset=identifier"`
+	Categories  []productCategory `json:"Categories" validate:"required,max=20"`
+}
+
+// product creates product struct from attributes of request
+func (p *productRequest) product() models.Product {
+	return models.Product{
+		Name:        p.Name,
+		Price:       p.Price,
+		Currency:    p.Currency,
+		Description: p.Description,
+		SKU:         p.SKU,
+	}
+}
+
+// categories returns categories of product listed in request
+func (p *productRequest) categories() []models.Category {
+	categories := make([]models.Category, len(p.Categories))
+	for i, category := range p.Categories {
+		categories[i] = models.Category{Name: category.Name}
+	}
+	return categories
+}
+
+// productDocument is document of product changed by PATCH requests, see PatchProductHandler
+// It holds fields of product that can be changed, named as in responses to GET requests
+type productDocument struct {
+	Name        string      `json:"name" validate:"trim,nfc,required,max=200,charset=name"`
+	Price       int64       `json:"price" validate:"min=0"`
+	Currency    string      `json:"currency" validate:"trim,min=3,max=3,charset=upper"`
+	Description string      `json:"description" validate:"trim,nfc,max=5000,charset=text"`
+	SKU         string      `json:"sku" validate:"trim,max=64,charset=identifier"`
+	Categories  categorySet `json:"categories" validate:"required,max=20"`
+}
+
+// newProductDocument creates document of existing product
+func newProductDocument(details *models.ProductDetails) productDocument {
+	categories := make(categorySet, len(details.Categories))
+	for i, name := range details.Categories {
+		categories[i] = productCategory{Name: name}
+	}
+	return productDocument{
+		Name:        details.Name,
+		Price:       details.Price,
+		Currency:    details.Currency,
+		Description: details.Description,
+		SKU:         details.SKU,
+		Categories:  categories,
+	}
+}
+
+// product creates product struct from attributes of document
+func (d *productDocument) product() models.Product {
+	return models.Product{
+		Name:        d.Name,
+		Price:       d.Price,
+		Currency:    d.Currency,
+		Description: d.Description,
+		SKU:         d.SKU,
+	}
+}
+
+// categories returns categories of product listed in document
+func (d *productDocument) categories() []models.Category {
+	categories := make([]models.Category, len(d.Categories))
+	for i, category := range d.Categories {
+		categories[i] = models.Category{Name: category.Name}
+	}
+	return categories
+}
+
+// categorySet is set of product categories, in JSON it is object mapping category names to true, for example {"Dogs": true}
+// Unlike list, set lets patch add or remove single category by its name
+// Names mapped to false are not members of set, elements are sorted by name
+type categorySet []productCategory
+
+// MarshalJSON encodes set as JSON object
+func (s categorySet) MarshalJSON() ([]byte, error) {
+	members := make(map[string]bool, len(s))
+	for _, category := range s {
+		members[category.Name] = true
+	}
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON decodes set from JSON object
+func (s *categorySet) UnmarshalJSON(data []byte) error {
+	var members map[string]bool
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(members))
+	for name, member := range members {
+		if member {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	*s = make(categorySet, len(names))
+	for i, name := range names {
+		(*s)[i] = productCategory{Name: name}
+	}
+	return nil
+}
+
+// setRoleRequest is body of request to assign role to user
+type setRoleRequest struct {
+	Role string `json:"role" validate:"trim,required,oneof=viewer editor admin"`
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SearchHandler handles full-text product search requests
+// Reads search text from query parameter q, optional category filter from category and result count from limit
+// If successful, writes matched products ranked by relevance in JSON format to response;
+// otherwise writes error response
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// Check that search text is provided
+	options := models.SearchOptions{
+		Query:    strings.TrimSpace(query.Get("q")),
+		Category: query.Get("category"),
+	}
+	if options.Query == "" {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, models.MalformedError(models.CodeInvalidParameter, "query parameter q is required"))
+		return
+	}
+
+	// Parse optional limit of results
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			// Write error response with status code following from error
+			utils.WriteError(w, r, models.MalformedError(models.CodeInvalidParameter, "limit must be integer"))
+			return
+		}
+		options.Limit = limit
+	}
+
+	// Search products in database
+	results, err := models.SearchProducts(r.Context(), database.GetDB(), options)
+	if err != nil {
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// Output in JSON format list of matched products
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
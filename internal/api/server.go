@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"net"
+	"net/http"
+)
+
+// Server serves HTTP requests until its context is cancelled and then shuts down gracefully
+type Server struct {
+	config config.ServerConfig
+	server *http.Server
+}
+
+// NewServer creates server serving requests with specified handler, usually handler returned by NewHandler
+func NewServer(config config.ServerConfig, handler http.Handler) *Server {
+	return &Server{
+		config: config,
+		server: &http.Server{
+			Addr:              config.Addr,
+			Handler:           handler,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			ReadTimeout:       config.ReadTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+		},
+	}
+}
+
+// ListenAndServe listens on configured address and serves requests until context is cancelled
+// returns nil after graceful shutdown, or error if server could not start or shut down in time
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves requests accepted on listener until context is cancelled
+// On cancellation server stops accepting connections and waits up to ShutdownTimeout for in-flight requests
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		if s.config.TLSEnabled() {
+			serveErr <- s.server.ServeTLS(listener, s.config.TLSCertFile, s.config.TLSKeyFile)
+		} else {
+			serveErr <- s.server.Serve(listener)
+		}
+	}()
+
+	logger.Info("Server started", "addr", listener.Addr().String(), "tls", s.config.TLSEnabled())
+
+	select {
+	case err := <-serveErr:
+		// Server stopped on its own, for example because certificate could not be loaded
+		return err
+	case <-ctx.Done():
+	}
+
+	// Drain in-flight requests
+	logger.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		s.server.Close()
+		return err
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Info("Server stopped")
+	return nil
+}
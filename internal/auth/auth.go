@@ -1,34 +1,58 @@
 package auth
 
 import (
-	"encoding/json"
-	"errors"
+	"context"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
 	"github.com/MaximInnopolis/ProductCatalog/internal/database"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
 	"github.com/MaximInnopolis/ProductCatalog/internal/models"
 	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"github.com/MaximInnopolis/ProductCatalog/internal/validation"
 	"net/http"
 	"strings"
 )
 
+// errMissingAuthorization is returned when request to protected route has no Authorization header
+var errMissingAuthorization = models.UnauthorizedError(models.CodeMissingAuthorization, "authorization header is missing")
+
+// registerRequest is body of registration request
+// Passwords are limited to 72 characters, as bcrypt ignores anything after 72 bytes
+type registerRequest struct {
+	Username string `json:"username" validate:"trim,required,min=3,max=32,charset=identifier"`
+	Password string `json:"password" validate:"required,min=8,max=72"`
+}
+
+// loginRequest is body of login request
+// Only presence of credentials is checked, so that rules of registration are not disclosed on login
+type loginRequest struct {
+	Username string `json:"username" validate:"trim,required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// Configure sets up issuing and checking of tokens with secret key and token lifetime from configuration
+func Configure(config config.AuthConfig) {
+	models.ConfigureTokens(models.TokenSettings{SecretKey: config.SecretKey, TTL: config.TokenTTL})
+}
+
 // RegisterUserHandler handles user registration requests
 // Parses request body to extract user data, attempts to register user in database
 // If successful, writes success message to response; otherwise writes error response
 func RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse request body to get user data
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
+	var request registerRequest
+	err := validation.DecodeJSON(r, &request)
 	if err != nil {
-		// Write error response with bad request status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusBadRequest)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
+	user := models.User{Username: request.Username, Password: request.Password}
 
 	// Add user to database
-	err = models.RegisterUser(database.GetDB(), &user)
+	err = models.RegisterUser(r.Context(), database.GetDB(), &user)
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 	// Write success message to response
@@ -40,19 +64,20 @@ func RegisterUserHandler(w http.ResponseWriter, r *http.Request) {
 // If successful, writes success message along with the token to the response; otherwise writes error response
 func LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse request body to get user data
-	var user models.User
-	err := json.NewDecoder(r.Body).Decode(&user)
+	var request loginRequest
+	err := validation.DecodeJSON(r, &request)
 	if err != nil {
-		// Write error response with bad request status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusBadRequest)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
+	user := models.User{Username: request.Username, Password: request.Password}
 
 	// Login user and generate token
-	token, err := models.LoginUser(database.GetDB(), &user)
+	token, err := models.LoginUser(r.Context(), database.GetDB(), &user)
 	if err != nil {
-		// Write error response with internal server error status code
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+		// Write error response with status code following from error
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -60,32 +85,59 @@ func LoginUserHandler(w http.ResponseWriter, r *http.Request) {
 	utils.WriteTokenJSONResponse(w, http.StatusOK, "Successfully logged in", token)
 }
 
-// RequireValidToken checks if request contains valid authentication token
-// Extracts token from Authorization header and verifies its validity against the database
-// If token is missing or invalid, writes appropriate error response and returns false; otherwise returns true
-func RequireValidToken(w http.ResponseWriter, r *http.Request) bool {
-	// Extract token from Authorization header
-	authHeader := r.Header.Get("Authorization")
-	// Check if the Authorization header is missing
-	if authHeader == "" {
-		logger.Println("Authorization header is missing")
-		utils.WriteErrorJSONResponse(w, errors.New("authorization header is missing"), http.StatusInternalServerError)
-		return false
-	}
-	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+// contextKey is type of keys under which auth package stores values in request context
+type contextKey string
 
-	// Check if token is valid
-	authenticated, err := models.IsTokenValid(database.GetDB(), tokenString)
-	if err != nil {
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
-		return false
-	}
+// claimsContextKey is key of token claims of authenticated user in request context
+const claimsContextKey contextKey = "claims"
+
+// RequireRole wraps handler so that it is served only to requests with valid token of user having at least specified role
+// Role is read from database rather than from token, so that changes of role take effect immediately
+// Writes unauthorized response if token is missing or invalid or its user no longer exists,
+// and forbidden response if role is insufficient
+// Claims of authenticated user with current role are passed to handler in request context, see ClaimsFromContext
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Extract token from Authorization header
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" {
+			logger.FromContext(r.Context()).Info("Authorization header is missing")
+			utils.WriteError(w, r, errMissingAuthorization)
+			return
+		}
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+
+		// Parse token and extract claims
+		claims, err := models.ParseToken(tokenString)
+		if err != nil {
+			logger.FromContext(r.Context()).Info("Invalid token", "error", err)
+			utils.WriteError(w, r, err)
+			return
+		}
 
-	// If token is not valid, return unauthorized status
-	if !authenticated {
-		utils.WriteErrorJSONResponse(w, errors.New("unauthorized"), http.StatusUnauthorized)
-		return false
+		// Records logged while serving request carry name of authenticated user
+		ctx := logger.With(r.Context(), "user", claims.Username)
+
+		// Look up current role of user, as it may have changed since token was issued
+		claims.Role, err = models.GetUserRole(ctx, database.GetDB(), claims.UserID, claims.Username)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+
+		// Check if user role grants access to route
+		if !models.HasRole(claims.Role, role) {
+			logger.FromContext(ctx).Info("User role does not grant access", "role", claims.Role, "required", role)
+			utils.WriteError(w, r, models.ForbiddenError(models.CodeForbidden, "%s role required", role))
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(ctx, claimsContextKey, claims)))
 	}
+}
 
-	return true
+// ClaimsFromContext returns token claims of user authenticated by RequireRole
+func ClaimsFromContext(ctx context.Context) (*models.TokenClaims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(*models.TokenClaims)
+	return claims, ok
 }
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// MinSecretKeyLength is minimal length of secret key signing JWT tokens
+const MinSecretKeyLength = 32
+
+// MinPasswordLength and MaxPasswordLength limit length of admin password the same way as passwords of registered users
+// Passwords are limited to 72 characters, as bcrypt ignores anything after 72 bytes
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// Config holds settings of catalog service
+// Settings are loaded by Load from defaults, optional YAML file, environment variables and command line flags,
+// each source overriding previous ones
+type Config struct {
+	Database  DatabaseConfig  `yaml:"database"`
+	Auth      AuthConfig      `yaml:"auth"`
+	Server    ServerConfig    `yaml:"server"`
+	Collector CollectorConfig `yaml:"collector"`
+	Backup    BackupConfig    `yaml:"backup"`
+	Log       LogConfig       `yaml:"log"`
+}
+
+// DatabaseConfig holds settings of database connection
+type DatabaseConfig struct {
+	// Path is path of SQLite database file
+	Path string `yaml:"path"`
+}
+
+// AuthConfig holds settings of user authentication
+type AuthConfig struct {
+	// SecretKey signs JWT tokens and must be at least MinSecretKeyLength characters long
+	SecretKey string `yaml:"secret_key"`
+	// TokenTTL is lifetime of issued tokens
+	TokenTTL time.Duration `yaml:"token_ttl"`
+	// AdminUsername and AdminPassword describe administrator created on start if set
+	AdminUsername string `yaml:"admin_username"`
+	AdminPassword string `yaml:"admin_password"`
+}
+
+// ServerConfig holds settings of HTTP server
+type ServerConfig struct {
+	// Addr is TCP address to listen on, for example ":8080"
+	Addr string `yaml:"addr"`
+	// ReadHeaderTimeout, ReadTimeout, WriteTimeout and IdleTimeout are passed to http.Server
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout limits how long in-flight requests are drained on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set
+	TLSCertFile string `yaml:"tls_cert_file"`
+	TLSKeyFile  string `yaml:"tls_key_file"`
+	// AccessLog is format of access log: AccessLogCombined, AccessLogJSON or AccessLogOff
+	AccessLog string `yaml:"access_log"`
+	// MaxBodyBytes limits size of request body
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+}
+
+// Access log formats
+const (
+	// AccessLogCombined logs requests in Apache combined log format
+	AccessLogCombined = "combined"
+	// AccessLogJSON logs requests as records with attributes, which are JSON objects with JSON log format
+	AccessLogJSON = "json"
+	// AccessLogOff disables access log
+	AccessLogOff = "off"
+)
+
+// TLSEnabled reports whether server serves HTTPS
+func (c ServerConfig) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// CollectorConfig holds settings of data collection
+type CollectorConfig struct {
+	// SourcesFile is path of JSON file listing sources, emojihub is collected hourly if empty
+	SourcesFile string `yaml:"sources_file"`
+	// HTTPTimeout limits single request to source
+	HTTPTimeout time.Duration `yaml:"http_timeout"`
+	// HTTPRetries is number of retries of failed request to source
+	HTTPRetries int `yaml:"http_retries"`
+	// RunsRetention is how long history of collection runs is kept
+	RunsRetention time.Duration `yaml:"runs_retention"`
+	// CleanupSchedule is schedule of removing old collection runs
+	CleanupSchedule string `yaml:"cleanup_schedule"`
+	// DegradeReadiness makes readiness probe report degraded state when last collection run failed
+	DegradeReadiness bool `yaml:"degrade_readiness"`
+}
+
+// BackupConfig holds settings of database backups
+type BackupConfig struct {
+	// Dir is directory backups are written to, backups are disabled if empty
+	Dir string `yaml:"dir"`
+	// Schedule is schedule of backups
+	Schedule string `yaml:"schedule"`
+	// Keep is number of most recent backups kept, zero keeps all
+	Keep int `yaml:"keep"`
+}
+
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogConfig holds settings of application log
+type LogConfig struct {
+	// Level is minimal level of logged records: debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is LogFormatText or LogFormatJSON
+	Format string `yaml:"format"`
+	// Outputs are "stdout", "stderr" or paths of log files, every record is written to all of them
+	Outputs []string `yaml:"outputs"`
+	// MaxSizeMB is size in megabytes after which log file is rotated, zero disables rotation by size
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// MaxAge is time after which log file is rotated, zero disables rotation by age
+	MaxAge time.Duration `yaml:"max_age"`
+	// MaxBackups is number of most recent rotated files kept, zero keeps all
+	MaxBackups int `yaml:"max_backups"`
+	// Compress gzips rotated files
+	Compress bool `yaml:"compress"`
+}
+
+// Default returns configuration used when no other source sets value
+func Default() *Config {
+	return &Config{
+		Database: DatabaseConfig{
+			Path: "./data/database.db",
+		},
+		Auth: AuthConfig{
+			TokenTTL: 24 * time.Hour,
+		},
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			AccessLog:         AccessLogCombined,
+			MaxBodyBytes:      1 << 20,
+		},
+		Collector: CollectorConfig{
+			HTTPTimeout:     30 * time.Second,
+			HTTPRetries:     3,
+			RunsRetention:   30 * 24 * time.Hour,
+			CleanupSchedule: "@daily",
+		},
+		Backup: BackupConfig{
+			Schedule: "@daily",
+			Keep:     7,
+		},
+		Log: LogConfig{
+			Level:      "info",
+			Format:     LogFormatText,
+			Outputs:    []string{"stdout", "logs/app.log"},
+			MaxSizeMB:  100,
+			MaxBackups: 7,
+		},
+	}
+}
+
+// Validate checks that configuration is complete and consistent
+// returns error listing every invalid setting
+func (c *Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Database.Path != "", "database path is required")
+
+	check(c.Auth.SecretKey != "", "secret key is required")
+	check(c.Auth.SecretKey == "" || len(c.Auth.SecretKey) >= MinSecretKeyLength,
+		"secret key must be at least %d characters long", MinSecretKeyLength)
+	check(c.Auth.TokenTTL > 0, "token TTL must be positive")
+	check(c.Auth.AdminUsername == "" || c.Auth.AdminPassword != "", "admin password is required with admin username")
+	check(c.Auth.AdminPassword == "" || (len(c.Auth.AdminPassword) >= MinPasswordLength && len(c.Auth.AdminPassword) <= MaxPasswordLength),
+		"admin password must be %d to %d characters long", MinPasswordLength, MaxPasswordLength)
+
+	check(c.Server.Addr != "", "server address is required")
+	check((c.Server.TLSCertFile == "") == (c.Server.TLSKeyFile == ""), "TLS certificate and key files must be set together")
+	check(c.Server.ReadHeaderTimeout >= 0 && c.Server.ReadTimeout >= 0 && c.Server.WriteTimeout >= 0 &&
+		c.Server.IdleTimeout >= 0, "server timeouts must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "shutdown timeout must be positive")
+	check(c.Server.AccessLog == AccessLogCombined || c.Server.AccessLog == AccessLogJSON || c.Server.AccessLog == AccessLogOff,
+		"access log format must be %s, %s or %s", AccessLogCombined, AccessLogJSON, AccessLogOff)
+	check(c.Server.MaxBodyBytes > 0, "maximum size of request body must be positive")
+
+	check(c.Collector.HTTPTimeout > 0, "collector HTTP timeout must be positive")
+	check(c.Collector.HTTPRetries >= 0, "collector HTTP retries must not be negative")
+	check(c.Collector.RunsRetention > 0, "collector runs retention must be positive")
+	check(c.Collector.CleanupSchedule != "", "cleanup schedule is required")
+
+	check(c.Backup.Dir == "" || c.Backup.Schedule != "", "backup schedule is required with backup directory")
+	check(c.Backup.Keep >= 0, "number of kept backups must not be negative")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "unknown log level %q", c.Log.Level)
+	check(c.Log.Format == LogFormatText || c.Log.Format == LogFormatJSON,
+		"log format must be %s or %s", LogFormatText, LogFormatJSON)
+	check(len(c.Log.Outputs) > 0, "at least one log output is required")
+	check(c.Log.MaxSizeMB >= 0 && c.Log.MaxAge >= 0 && c.Log.MaxBackups >= 0,
+		"log rotation settings must not be negative")
+
+	return errors.Join(problems...)
+}
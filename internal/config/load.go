@@ -0,0 +1,192 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// binding ties setting to environment variable and command line flag, either of which may be empty
+type binding struct {
+	env    string
+	flag   string
+	usage  string
+	target interface{}
+}
+
+// bindings returns bindings of all settings of configuration
+func bindings(c *Config) []binding {
+	return []binding{
+		{"DB_PATH", "db-path", "path of SQLite database file", &c.Database.Path},
+
+		{"SECRET_KEY", "", "", &c.Auth.SecretKey},
+		{"TOKEN_TTL", "token-ttl", "lifetime of issued tokens", &c.Auth.TokenTTL},
+		{"ADMIN_USERNAME", "admin-username", "administrator created on start", &c.Auth.AdminUsername},
+		{"ADMIN_PASSWORD", "", "", &c.Auth.AdminPassword},
+
+		{"HTTP_ADDR", "addr", "address to listen on", &c.Server.Addr},
+		{"HTTP_READ_HEADER_TIMEOUT", "read-header-timeout", "timeout of reading request headers", &c.Server.ReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", "read-timeout", "timeout of reading request", &c.Server.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", "write-timeout", "timeout of writing response", &c.Server.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", "idle-timeout", "timeout of idle keep-alive connection", &c.Server.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", "shutdown-timeout", "timeout of draining requests on shutdown", &c.Server.ShutdownTimeout},
+		{"TLS_CERT_FILE", "tls-cert", "TLS certificate file", &c.Server.TLSCertFile},
+		{"TLS_KEY_FILE", "tls-key", "TLS key file", &c.Server.TLSKeyFile},
+		{"HTTP_ACCESS_LOG", "access-log", "access log format: combined, json or off", &c.Server.AccessLog},
+		{"HTTP_MAX_BODY_BYTES", "max-body-bytes", "maximum size of request body in bytes", &c.Server.MaxBodyBytes},
+
+		{"COLLECTOR_SOURCES", "collector-sources", "JSON file listing collector sources", &c.Collector.SourcesFile},
+		{"COLLECTOR_HTTP_TIMEOUT", "collector-http-timeout", "timeout of request to source", &c.Collector.HTTPTimeout},
+		{"COLLECTOR_HTTP_RETRIES", "collector-http-retries", "retries of failed request to source", &c.Collector.HTTPRetries},
+		{"COLLECTOR_RUNS_RETENTION", "collector-runs-retention", "how long collection runs are kept", &c.Collector.RunsRetention},
+		{"CLEANUP_SCHEDULE", "cleanup-schedule", "schedule of collection runs cleanup", &c.Collector.CleanupSchedule},
+		{"COLLECTOR_DEGRADE_READINESS", "collector-degrade-readiness", "report degraded readiness when last collection failed", &c.Collector.DegradeReadiness},
+
+		{"BACKUP_DIR", "backup-dir", "directory of database backups", &c.Backup.Dir},
+		{"BACKUP_SCHEDULE", "backup-schedule", "schedule of database backups", &c.Backup.Schedule},
+		{"BACKUP_KEEP", "backup-keep", "number of kept backups", &c.Backup.Keep},
+
+		{"LOG_LEVEL", "log-level", "minimal level of logged records", &c.Log.Level},
+		{"LOG_FORMAT", "log-format", "format of log records, text or json", &c.Log.Format},
+		{"LOG_OUTPUTS", "log-outputs", "comma-separated log outputs: stdout, stderr or file paths", &c.Log.Outputs},
+		{"LOG_MAX_SIZE_MB", "log-max-size-mb", "size in megabytes after which log file is rotated", &c.Log.MaxSizeMB},
+		{"LOG_MAX_AGE", "log-max-age", "time after which log file is rotated", &c.Log.MaxAge},
+		{"LOG_MAX_BACKUPS", "log-max-backups", "number of kept rotated log files", &c.Log.MaxBackups},
+		{"LOG_COMPRESS", "log-compress", "gzip rotated log files", &c.Log.Compress},
+	}
+}
+
+// Load builds configuration from, in order of increasing priority,
+// defaults, YAML file given by -config flag or CONFIG_FILE variable, environment variables and command line flags
+// Variables from .env file in working directory are added to environment if file exists, without overriding set ones
+// Configuration is not validated, see Validate
+// returns configuration and command line arguments remaining after flags
+func Load(name string, args []string) (*Config, []string, error) {
+	config := Default()
+	configBindings := bindings(config)
+
+	// Parse flags first to find configuration file, but apply them last
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFile := flags.String("config", "", "YAML configuration file")
+	type flagValue struct {
+		binding binding
+		value   string
+	}
+	var flagValues []flagValue
+	for _, b := range configBindings {
+		if b.flag == "" {
+			continue
+		}
+		b := b
+		flags.Func(b.flag, b.usage, func(value string) error {
+			flagValues = append(flagValues, flagValue{b, value})
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// Variables from .env file
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("loading .env file: %w", err)
+	}
+
+	// Configuration file
+	path := *configFile
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(config, path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Environment variables, empty ones are treated as not set
+	for _, b := range configBindings {
+		if value := os.Getenv(b.env); b.env != "" && value != "" {
+			if err := setValue(b.target, value); err != nil {
+				return nil, nil, fmt.Errorf("invalid %s: %w", b.env, err)
+			}
+		}
+	}
+
+	// Command line flags
+	for _, f := range flagValues {
+		if err := setValue(f.binding.target, f.value); err != nil {
+			return nil, nil, fmt.Errorf("invalid -%s: %w", f.binding.flag, err)
+		}
+	}
+
+	return config, flags.Args(), nil
+}
+
+// loadFile reads YAML configuration file into configuration, rejecting unknown keys
+func loadFile(config *Config, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading configuration file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parsing configuration file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setValue parses value according to type of target and stores it
+func setValue(target interface{}, value string) error {
+	switch target := target.(type) {
+	case *string:
+		*target = value
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+	case *int64:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+	case *bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+	case *time.Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*target = parsed
+	case *[]string:
+		// Comma-separated list, empty items are dropped
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*target = items
+	default:
+		return fmt.Errorf("unsupported setting type %T", target)
+	}
+	return nil
+}
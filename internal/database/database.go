@@ -4,27 +4,35 @@ import (
 	"database/sql"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
 	_ "github.com/mattn/go-sqlite3"
+	"strconv"
+	"strings"
 )
 
 var db *sql.DB
 
+// busyTimeout is time in milliseconds connection waits for lock held by another connection
+const busyTimeout = 5000
+
 // Init initializes database connection using provided database path
+// Foreign key enforcement is turned on for every connection, as SQLite disables it by default
+// Transactions take write lock when they begin and database uses write-ahead log,
+// so concurrent read-then-write transactions wait for each other instead of failing with "database is locked"
 // Returns  error if  connection cannot be established
 func Init(dbPath string) error {
 	var err error
-	db, err = sql.Open("sqlite3", dbPath)
+	db, err = sql.Open("sqlite3", withConnectionOptions(dbPath))
 	if err != nil {
-		logger.Printf("Failed to open database connection: %v", err)
+		logger.Error("Failed to open database connection", "error", err)
 		return err
 	}
 
 	// Database connection check
 	if err := db.Ping(); err != nil {
-		logger.Printf("Failed to ping database: %v", err)
+		logger.Error("Failed to ping database", "error", err)
 		return err
 	}
 
-	logger.Println("Database connection established")
+	logger.Info("Database connection established", "path", dbPath)
 
 	return nil
 }
@@ -33,9 +41,9 @@ func Init(dbPath string) error {
 func Close() {
 	if db != nil {
 		if err := db.Close(); err != nil {
-			logger.Println("Error closing database connection:", err)
+			logger.Error("Error closing database connection", "error", err)
 		} else {
-			logger.Println("Database connection closed")
+			logger.Info("Database connection closed")
 		}
 	}
 }
@@ -44,3 +52,13 @@ func Close() {
 func GetDB() *sql.DB {
 	return db
 }
+
+// withConnectionOptions appends driver options enabling foreign key enforcement, immediate transactions,
+// busy timeout and write-ahead log to database path
+func withConnectionOptions(dbPath string) string {
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	return dbPath + separator + "_foreign_keys=on&_txlock=immediate&_journal_mode=WAL&_busy_timeout=" + strconv.Itoa(busyTimeout)
+}
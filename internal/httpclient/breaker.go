@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is circuit breaker counting consecutive failed requests
+// When failures reach threshold, breaker opens and rejects requests until open timeout passes
+// After that single trial request is let through, its outcome closes breaker or opens it again
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// allow reports whether request may be sent at specified time
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// success closes breaker
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.trial = false
+}
+
+// failure records failed request and opens breaker if failures reached threshold or trial request failed
+func (b *breaker) failure(now time.Time, threshold int, openTimeout time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.trial || (threshold > 0 && b.failures >= threshold) {
+		b.openUntil = now.Add(openTimeout)
+		b.trial = false
+	}
+}
+
+// abandon lets next request be trial one if trial request ended without outcome
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
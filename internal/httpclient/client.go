@@ -0,0 +1,164 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without sending request while circuit breaker of key is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Config holds settings of outbound HTTP client
+type Config struct {
+	// Timeout limits single attempt including reading response headers and body
+	Timeout time.Duration
+	// MaxRetries is number of additional attempts after failed one
+	MaxRetries int
+	// BaseDelay is delay before first retry, doubled for each next retry
+	BaseDelay time.Duration
+	// MaxDelay caps delay between retries
+	MaxDelay time.Duration
+	// FailureThreshold is number of consecutive failed requests that opens circuit breaker
+	FailureThreshold int
+	// OpenTimeout is how long circuit breaker stays open before letting trial request through
+	OpenTimeout time.Duration
+}
+
+// DefaultConfig returns settings suitable for collecting data from public APIs
+func DefaultConfig() Config {
+	return Config{
+		Timeout:          30 * time.Second,
+		MaxRetries:       3,
+		BaseDelay:        500 * time.Millisecond,
+		MaxDelay:         10 * time.Second,
+		FailureThreshold: 5,
+		OpenTimeout:      time.Minute,
+	}
+}
+
+// Client sends outbound HTTP requests with timeouts, retries and circuit breakers
+// Requests are retried on network errors and 5xx responses with jittered exponential backoff
+// Every key, for example name of source, has its own circuit breaker
+type Client struct {
+	config Config
+	http   *http.Client
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// New creates client with specified settings
+func New(config Config) *Client {
+	return &Client{
+		config:   config,
+		http:     &http.Client{Timeout: config.Timeout},
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Get sends GET request to url on behalf of key
+// returns response with 2xx-4xx status or error if all attempts failed or circuit breaker of key is open
+// Caller must close body of returned response
+func (c *Client) Get(ctx context.Context, key, url string) (*http.Response, error) {
+	breaker := c.breaker(key)
+	if !breaker.allow(time.Now()) {
+		return nil, fmt.Errorf("%s: %w", key, ErrCircuitOpen)
+	}
+
+	response, err := c.getWithRetries(ctx, url)
+	if err != nil {
+		// Cancellation by caller says nothing about health of remote side
+		if ctx.Err() != nil {
+			breaker.abandon()
+		} else {
+			breaker.failure(time.Now(), c.config.FailureThreshold, c.config.OpenTimeout)
+		}
+		return nil, err
+	}
+
+	breaker.success()
+	return response, nil
+}
+
+// getWithRetries sends GET request until it succeeds, fails with non-retryable error or attempts run out
+func (c *Client) getWithRetries(ctx context.Context, url string) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := c.http.Do(request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		if response.StatusCode >= http.StatusInternalServerError {
+			// Drain body so that connection can be reused by next attempt
+			io.Copy(io.Discard, response.Body)
+			response.Body.Close()
+			lastErr = fmt.Errorf("%s responded with status %s", url, response.Status)
+			continue
+		}
+
+		return response, nil
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", c.config.MaxRetries+1, lastErr)
+}
+
+// backoff returns random delay before retry, between zero and exponentially growing capped bound
+func (c *Client) backoff(attempt int) time.Duration {
+	bound := c.config.MaxDelay
+	if shift := attempt - 1; shift < 32 {
+		if delay := c.config.BaseDelay << shift; delay > 0 && delay < bound {
+			bound = delay
+		}
+	}
+	if bound <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(bound) + 1))
+}
+
+// breaker returns circuit breaker of key, creating it on first use
+func (c *Client) breaker(key string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[key]
+	if !ok {
+		b = &breaker{}
+		c.breakers[key] = b
+	}
+	return b
+}
+
+// sleep waits for specified duration or until context is done
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
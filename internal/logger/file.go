@@ -0,0 +1,280 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat is format of rotation time in names of rotated files, which sorts in chronological order
+const rotatedTimeFormat = "20060102T150405.000Z"
+
+// FileOptions holds rotation settings of log file
+type FileOptions struct {
+	// MaxSize is size in bytes after which file is rotated, zero disables rotation by size
+	MaxSize int64
+	// MaxAge is time after which file is rotated, counted from when file was opened, zero disables rotation by age
+	MaxAge time.Duration
+	// MaxBackups is number of most recent rotated files kept, zero keeps all
+	MaxBackups int
+	// Compress gzips rotated files
+	Compress bool
+}
+
+// File is log file rotated when it grows too big or too old
+// Current file is renamed to name with rotation time, for example app-20240102T150405.000Z.log,
+// and new file is opened at original path
+// Rotated files are compressed and pruned in background
+type File struct {
+	path    string
+	options FileOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// cleanupMu serializes compressing and pruning of rotated files, cleanup tracks running cleanups
+	cleanupMu sync.Mutex
+	cleanup   sync.WaitGroup
+}
+
+// OpenFile opens log file at path for appending, creating file and its directory if needed
+func OpenFile(path string, options FileOptions) (*File, error) {
+	f := &File{path: path, options: options}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write writes p to file, rotating file first if p would make it exceed maximum size or file is too old
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.needsRotation(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates file immediately
+func (f *File) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Reopen opens file at same path again and closes previously opened file
+// Used after file was moved away by external tool such as logrotate
+// If file cannot be opened, records keep going to previously opened file
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	previous := f.file
+	if err := f.open(); err != nil {
+		return err
+	}
+	return previous.Close()
+}
+
+// Close closes file and waits until rotated files are compressed and pruned
+func (f *File) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.cleanup.Wait()
+	return err
+}
+
+// open opens file at path for appending
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+// needsRotation reports whether file has to be rotated before writing n more bytes
+// Empty file is never rotated, so single record larger than maximum size is still written
+func (f *File) needsRotation(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.options.MaxSize > 0 && f.size+n > f.options.MaxSize {
+		return true
+	}
+	return f.options.MaxAge > 0 && time.Since(f.openedAt) >= f.options.MaxAge
+}
+
+// rotate renames current file to name with rotation time and opens new file, caller must hold f.mu
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	// Rotated files must not overwrite each other even if file is rotated several times within millisecond
+	at := time.Now()
+	rotated := f.rotatedName(at)
+	for fileExists(rotated) || fileExists(rotated+".gz") {
+		at = at.Add(time.Millisecond)
+		rotated = f.rotatedName(at)
+	}
+	if err := os.Rename(f.path, rotated); err != nil {
+		// Keep writing to current file rather than losing records
+		if openErr := f.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("rotating log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.cleanup.Add(1)
+	go func() {
+		defer f.cleanup.Done()
+		f.cleanupMu.Lock()
+		defer f.cleanupMu.Unlock()
+
+		if f.options.Compress {
+			if err := compressFile(rotated); err != nil {
+				fmt.Fprintf(os.Stderr, "compressing rotated log file %s: %v\n", rotated, err)
+			}
+		}
+		if err := f.prune(); err != nil {
+			fmt.Fprintf(os.Stderr, "removing old log files: %v\n", err)
+		}
+	}()
+	return nil
+}
+
+// rotatedName returns path of file rotated at specified time
+func (f *File) rotatedName(at time.Time) string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-" + at.UTC().Format(rotatedTimeFormat) + ext
+}
+
+// prune removes all but MaxBackups most recent rotated files
+func (f *File) prune() error {
+	if f.options.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := f.backups()
+	if err != nil {
+		return err
+	}
+	if len(backups) <= f.options.MaxBackups {
+		return nil
+	}
+	for _, backup := range backups[:len(backups)-f.options.MaxBackups] {
+		if err := os.Remove(backup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backups returns paths of rotated files, compressed or not, oldest first
+func (f *File) backups() ([]string, error) {
+	dir := filepath.Dir(f.path)
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".gz")
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, entry.Name()))
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// fileExists reports whether file exists at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile replaces file with its gzipped copy named path.gz
+func compressFile(path string) (err error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			target.Close()
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	writer := gzip.NewWriter(target)
+	if _, err = io.Copy(writer, source); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	if err = target.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -1,41 +1,164 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
 	"io"
-	"log"
+	"log/slog"
 	"os"
+	"sync"
 )
 
 var (
-	logger  *log.Logger
-	logFile *os.File
+	mu sync.RWMutex
+	// logger is base logger of application, writing text records of info level to stdout until Configure is called
+	logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	// files are log files opened by Configure
+	files []*File
 )
 
-const logPath = "logs/app.log"
+// Configure replaces base logger with logger writing records of configured level and format to configured outputs
+// Output is "stdout", "stderr" or path of file, which is created together with its directory if needed
+// and rotated according to rotation settings
+// Files opened by previous call are closed
+func Configure(cfg config.LogConfig) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+
+	// Open all outputs
+	var writers []io.Writer
+	var opened []*File
+	fileOptions := FileOptions{
+		MaxSize:    int64(cfg.MaxSizeMB) << 20,
+		MaxAge:     cfg.MaxAge,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	}
+	for _, output := range cfg.Outputs {
+		switch output {
+		case "stdout":
+			writers = append(writers, os.Stdout)
+		case "stderr":
+			writers = append(writers, os.Stderr)
+		default:
+			file, err := OpenFile(output, fileOptions)
+			if err != nil {
+				closeFiles(opened)
+				return err
+			}
+			opened = append(opened, file)
+			writers = append(writers, file)
+		}
+	}
 
-func init() {
-	// Create a directory for log files
-	os.MkdirAll("logs", os.ModePerm)
-	// Create a file for log messages
-	var err error
-	logFile, err = os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		log.Fatalf("Failed to open log file: %v", err)
+	// Create handler of configured format
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch cfg.Format {
+	case config.LogFormatText:
+		handler = slog.NewTextHandler(io.MultiWriter(writers...), options)
+	case config.LogFormatJSON:
+		handler = slog.NewJSONHandler(io.MultiWriter(writers...), options)
+	default:
+		closeFiles(opened)
+		return fmt.Errorf("unknown log format %q", cfg.Format)
 	}
-	//defer logFile.Close()
 
-	// Initialize the logger
-	logger = log.New(io.MultiWriter(logFile, os.Stdout), "", log.Ldate|log.Ltime|log.Lshortfile)
+	mu.Lock()
+	previous := files
+	logger = slog.New(handler)
+	files = opened
+	mu.Unlock()
+
+	closeFiles(previous)
+	return nil
 }
 
+// Close closes log files, records are written to stdout afterwards
 func Close() error {
-	return logFile.Close()
+	mu.Lock()
+	previous := files
+	logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
+	files = nil
+	mu.Unlock()
+
+	return closeFiles(previous)
+}
+
+// Reopen reopens all log files at their paths, so that files moved away by external tool such as logrotate
+// are replaced by new ones
+func Reopen() error {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var firstErr error
+	for _, file := range files {
+		if err := file.Reopen(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
-func Printf(s string, v ...interface{}) {
-	logger.Printf(s, v...)
+// Default returns base logger of application, used for records not related to any request or job
+func Default() *slog.Logger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return logger
 }
 
-func Println(v ...interface{}) {
-	logger.Println(v...)
+// contextKey is type of key under which logger is stored in context
+type contextKey struct{}
+
+// NewContext returns copy of context carrying specified logger
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns logger carried by context, or base logger if context carries none
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return Default()
+}
+
+// With returns copy of context whose logger adds specified attributes to every record,
+// for example request ID or name of job
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// Debug logs record of debug level with base logger
+func Debug(msg string, args ...any) {
+	Default().Debug(msg, args...)
+}
+
+// Info logs record of info level with base logger
+func Info(msg string, args ...any) {
+	Default().Info(msg, args...)
+}
+
+// Warn logs record of warn level with base logger
+func Warn(msg string, args ...any) {
+	Default().Warn(msg, args...)
+}
+
+// Error logs record of error level with base logger
+func Error(msg string, args ...any) {
+	Default().Error(msg, args...)
+}
+
+// closeFiles closes all specified files and returns first error encountered
+func closeFiles(files []*File) error {
+	var firstErr error
+	for _, file := range files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
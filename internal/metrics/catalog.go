@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// catalogCollector counts products and categories in database whenever metrics are scraped
+type catalogCollector struct {
+	db *sql.DB
+
+	products   *prometheus.Desc
+	categories *prometheus.Desc
+	up         *prometheus.Desc
+}
+
+// newCatalogCollector creates collector counting products and categories in database
+func newCatalogCollector(db *sql.DB) *catalogCollector {
+	return &catalogCollector{
+		db: db,
+		products: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "products"),
+			"Number of products by state: active or discontinued.",
+			[]string{"state"}, nil,
+		),
+		categories: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "categories"),
+			"Number of categories.",
+			nil, nil,
+		),
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "counts", "scrape_success"),
+			"Whether products and categories were counted successfully.",
+			nil, nil,
+		),
+	}
+}
+
+// Describe sends descriptions of all metrics of collector
+func (c *catalogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.products
+	ch <- c.categories
+	ch <- c.up
+}
+
+// Collect counts products and categories and sends their numbers
+// Failure to query database is reported by scrape_success metric instead of failing whole scrape
+func (c *catalogCollector) Collect(ch chan<- prometheus.Metric) {
+	var active, discontinued, categories int
+	err := c.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM products WHERE discontinued_at IS NULL),
+			(SELECT COUNT(*) FROM products WHERE discontinued_at IS NOT NULL),
+			(SELECT COUNT(*) FROM categories)
+	`).Scan(&active, &discontinued, &categories)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.products, prometheus.GaugeValue, float64(active), "active")
+	ch <- prometheus.MustNewConstMetric(c.products, prometheus.GaugeValue, float64(discontinued), "discontinued")
+	ch <- prometheus.MustNewConstMetric(c.categories, prometheus.GaugeValue, float64(categories))
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+}
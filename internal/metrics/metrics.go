@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// namespace prefixes names of all metrics of catalog service
+const namespace = "catalog"
+
+// Outcomes of login attempt
+const (
+	LoginSucceeded       = "success"
+	LoginUnknownUser     = "unknown_user"
+	LoginInvalidPassword = "invalid_password"
+	LoginError           = "error"
+)
+
+// UnmatchedRoute is route label of requests not matched by any route, so that unknown paths do not create new series
+const UnmatchedRoute = "unmatched"
+
+var (
+	// registry holds all metrics exposed by Handler
+	registry = prometheus.NewRegistry()
+
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of served HTTP requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of served HTTP requests by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	collectorRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "collector",
+		Name:      "runs_total",
+		Help:      "Number of finished collection runs by source and status.",
+	}, []string{"source", "status"})
+
+	collectorDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "collector",
+		Name:      "run_duration_seconds",
+		Help:      "Duration of collection runs by source and status.",
+		Buckets:   prometheus.ExponentialBuckets(0.5, 2, 12),
+	}, []string{"source", "status"})
+
+	collectorProducts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "collector",
+		Name:      "products_total",
+		Help:      "Number of collected products by source and outcome: inserted, updated, unchanged, removed or failed.",
+	}, []string{"source", "outcome"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "logins_total",
+		Help:      "Number of login attempts by outcome.",
+	}, []string{"outcome"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		collectorRuns, collectorDuration, collectorProducts,
+		logins,
+	)
+}
+
+// Handler returns handler exposing metrics in Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records served HTTP request
+// Empty route is reported as UnmatchedRoute
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = UnmatchedRoute
+	}
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	httpRequests.With(labels).Inc()
+	httpDuration.With(labels).Observe(duration.Seconds())
+}
+
+// CollectorRun holds result of collection run reported by ObserveCollectorRun
+type CollectorRun struct {
+	Source   string
+	Status   string
+	Duration time.Duration
+	// Products maps outcome of product synchronization to number of products
+	Products map[string]int
+}
+
+// ObserveCollectorRun records finished collection run
+func ObserveCollectorRun(run CollectorRun) {
+	collectorRuns.WithLabelValues(run.Source, run.Status).Inc()
+	collectorDuration.WithLabelValues(run.Source, run.Status).Observe(run.Duration.Seconds())
+	for outcome, count := range run.Products {
+		collectorProducts.WithLabelValues(run.Source, outcome).Add(float64(count))
+	}
+}
+
+// ObserveLogin records login attempt with specified outcome
+func ObserveLogin(outcome string) {
+	logins.WithLabelValues(outcome).Inc()
+}
+
+// databaseCollectors are collectors of database registered by RegisterDatabase, guarded by databaseMu
+var (
+	databaseMu         sync.Mutex
+	databaseCollectors []prometheus.Collector
+)
+
+// RegisterDatabase exposes connection pool statistics of database and numbers of products and categories in it
+// Collectors of database registered by previous call are replaced
+func RegisterDatabase(db *sql.DB) error {
+	databaseMu.Lock()
+	defer databaseMu.Unlock()
+
+	for _, collector := range databaseCollectors {
+		registry.Unregister(collector)
+	}
+	databaseCollectors = nil
+
+	for _, collector := range []prometheus.Collector{
+		collectors.NewDBStatsCollector(db, "catalog"),
+		newCatalogCollector(db),
+	} {
+		if err := registry.Register(collector); err != nil {
+			return err
+		}
+		databaseCollectors = append(databaseCollectors, collector)
+	}
+	return nil
+}
@@ -1,24 +1,72 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"strings"
+	"time"
 )
 
 type Category struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Parent string `json:"parent,omitempty"`
+	// Version grows with every change of category, including rename of its parent
+	// UpdateCategory changes category only if stored version equals Version, unless Version is AnyVersion
+	Version int64 `json:"version"`
+}
+
+// CategoryNode represents category together with its subcategories in category tree
+type CategoryNode struct {
+	ID       int             `json:"id"`
+	Name     string          `json:"name"`
+	Children []*CategoryNode `json:"children"`
+}
+
+// reservedCategoryNames are names of category collection routes, such as GET /categories/list,
+// category with one of these names could not be read by its name
+var reservedCategoryNames = map[string]bool{"list": true, "tree": true}
+
+// checkCategoryName returns error of kind ErrValidation if category name is reserved
+func checkCategoryName(categoryName string) error {
+	if reservedCategoryNames[categoryName] {
+		return ValidationError(CodeReservedCategoryName, "category name %q is reserved", categoryName)
+	}
+	return nil
 }
 
 // AddCategory inserts new category into database using provided DB connection
+// If category has parent, new category is placed under parent category, which must already exist
 // Returns ID of newly inserted category and any error encountered
-func AddCategory(db *sql.DB, category *Category) (int64, error) {
-	// Execute INSERT query to add new category to database with provided name
-	query := "INSERT INTO categories (name) VALUES (?)"
-	result, err := db.Exec(query, category.Name)
+func AddCategory(ctx context.Context, db Executor, category *Category) (int64, error) {
+	// Check that category can be addressed by its name
+	if err := checkCategoryName(category.Name); err != nil {
+		logger.FromContext(ctx).Info("Cannot add category", "category", category.Name, "error", err)
+		return 0, err
+	}
+
+	// Resolve ID of parent category if one is specified
+	var parentID sql.NullInt64
+	if category.Parent != "" {
+		id, err := GetCategoryID(ctx, db, category.Parent)
+		if err != nil {
+			logger.FromContext(ctx).Error("Error getting parent category ID", "error", err)
+			return 0, parentNotFoundError(err)
+		}
+		parentID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	// Execute INSERT query to add new category to database with provided name and parent
+	query := "INSERT INTO categories (name, parent_id) VALUES (?, ?)"
+	result, err := db.ExecContext(ctx, query, category.Name, parentID)
+	if isUniqueViolation(err) {
+		logger.FromContext(ctx).Info("Category already exists", "category", category.Name)
+		return 0, ConflictError(CodeCategoryExists, "category %q already exists", category.Name).Wrap(err)
+	}
 	if err != nil {
-		logger.Println("Error inserting category into database:", err)
+		logger.FromContext(ctx).Error("Error inserting category into database", "error", err)
 		return 0, err
 	}
 
@@ -27,20 +75,22 @@ func AddCategory(db *sql.DB, category *Category) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	category.ID = int(categoryID)
+	category.Version = 1
 
-	logger.Println("Category added successfully")
+	logger.FromContext(ctx).Info("Category added", "category", category.Name, "id", categoryID)
 	// Return ID of newly inserted category and nil error
 	return categoryID, nil
 }
 
 // GetAllCategories retrieves all existing categories from database using provided DB connection
 // Returns slice of strings containing names of all categories and any error encountered
-func GetAllCategories(db *sql.DB) ([]string, error) {
+func GetAllCategories(ctx context.Context, db *sql.DB) ([]string, error) {
 	// Execute SELECT query to retrieve all category names from database
 	query := "SELECT name FROM categories"
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
-		logger.Println("Error querying categories:", err)
+		logger.FromContext(ctx).Error("Error querying categories", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -52,7 +102,7 @@ func GetAllCategories(db *sql.DB) ([]string, error) {
 		var category string
 		// Scan category name from current row into 'category' variable
 		if err := rows.Scan(&category); err != nil {
-			logger.Println("Error scanning category row:", err)
+			logger.FromContext(ctx).Error("Error scanning category row", "error", err)
 			return nil, err
 		}
 		// Append category name to 'categories' slice
@@ -60,72 +110,491 @@ func GetAllCategories(db *sql.DB) ([]string, error) {
 	}
 	// Check for any error occurred during iteration
 	if err := rows.Err(); err != nil {
-		logger.Println("Error iterating through category rows:", err)
+		logger.FromContext(ctx).Error("Error iterating through category rows", "error", err)
 		return nil, err
 	}
 
-	logger.Println("Successfully got all categories")
+	logger.FromContext(ctx).Debug("Got all categories", "count", len(categories))
 	// Return slice containing all category names and nil error
 	return categories, nil
 }
 
+// GetCategory retrieves category with specified name from database together with name of its parent
+// Returns category and any error encountered, error of kind ErrNotFound wrapping sql.ErrNoRows if category does not exist
+func GetCategory(ctx context.Context, db Executor, categoryName string) (*Category, error) {
+	query := `
+		SELECT c.id, c.name, parent.name, c.version
+		FROM categories c
+		LEFT JOIN categories parent ON parent.id = c.parent_id
+		WHERE c.name = ?
+	`
+	var category Category
+	var parent sql.NullString
+	err := db.QueryRowContext(ctx, query, categoryName).Scan(&category.ID, &category.Name, &parent, &category.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Debug("Category not found", "category", categoryName)
+		return nil, categoryNotFoundError(categoryName, err)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting category", "category", categoryName, "error", err)
+		return nil, err
+	}
+	category.Parent = parent.String
+
+	logger.FromContext(ctx).Debug("Got category", "category", categoryName, "id", category.ID)
+	return &category, nil
+}
+
 // UpdateCategory edits existing category in database with specified name
-// Takes database connection, current category name, and updated category information as parameters
-// Returns error if any occurred during update process
-func UpdateCategory(db *sql.DB, categoryName string, category *Category) error {
-	// Execute UPDATE query to update category name in database
-	query := "UPDATE categories SET name = ? WHERE name = ?"
-	result, err := db.Exec(query, category.Name, categoryName)
-	if err != nil {
-		logger.Println("Error updating category in database:", err)
+// Category is changed only if its stored version equals category.Version, which is then set to new version
+// Consists of several statements, so that versions of dependents change together with category,
+// callers should run it inside transaction (see WithTx)
+// Takes database connection or transaction, current category name, and updated category information as parameters
+// Returns error if any occurred during update process, error of kind ErrPreconditionFailed if version differs
+func UpdateCategory(ctx context.Context, db Executor, categoryName string, category *Category) error {
+	// Check that category can still be addressed by its new name
+	if err := checkCategoryName(category.Name); err != nil {
+		logger.FromContext(ctx).Info("Cannot update category", "category", categoryName, "error", err)
+		return err
+	}
+
+	// Check if category exists in database
+	categoryID, err := GetCategoryID(ctx, db, categoryName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.FromContext(ctx).Info("Category not found", "category", categoryName)
+			return categoryNotFoundError(categoryName, err)
+		}
+		return err
+	}
+
+	// Execute UPDATE query to update category name in database, if category still has expected version
+	query := "UPDATE categories SET name = ?, version = version + 1 WHERE id = ? AND " + versionCondition
+	result, err := db.ExecContext(ctx, query, append([]interface{}{category.Name, categoryID}, versionArgs(category.Version)...)...)
+	if isUniqueViolation(err) {
+		logger.FromContext(ctx).Info("Category already exists", "category", category.Name)
+		return ConflictError(CodeCategoryExists, "category %q already exists", category.Name).Wrap(err)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Error updating category in database", "error", err)
 		return err
 	}
 
 	// Get number of rows affected by UPDATE operation
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		logger.Println("Error getting rows affected:", err)
+		logger.FromContext(ctx).Error("Error getting rows affected", "error", err)
 		return err
 	}
 
-	// Check if no rows were affected by UPDATE operation
+	// Category exists, so no rows are affected only if its version differs
 	if rowsAffected == 0 {
-		logger.Printf("Category %s not found in database", categoryName)
-		return errors.New("no rows affected, category not found")
+		logger.FromContext(ctx).Info("Category version is outdated", "category", categoryName, "version", category.Version)
+		return versionMismatchError("category", categoryName)
 	}
 
-	logger.Println("Category updated successfully")
-	// Return error indicating that no rows were affected
+	// Subcategories and products show name of category, so they change as well
+	if err := touchCategoryDependents(ctx, db, categoryID); err != nil {
+		return err
+	}
+
+	// Read version assigned by update
+	category.ID = int(categoryID)
+	err = db.QueryRowContext(ctx, "SELECT version FROM categories WHERE id = ?", categoryID).Scan(&category.Version)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting category version", "error", err)
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Category updated", "category", categoryName, "name", category.Name)
 	return nil
 }
 
+// touchCategoryDependents increases versions of subcategories and products of category with specified ID
+// Modification time of products is updated as well, categories do not track it
+func touchCategoryDependents(ctx context.Context, db Executor, categoryID int64) error {
+	_, err := db.ExecContext(ctx, "UPDATE categories SET version = version + 1 WHERE parent_id = ?", categoryID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error updating versions of subcategories", "error", err)
+		return err
+	}
+
+	query := `
+		UPDATE products SET version = version + 1, updated_at = ?
+		WHERE id IN (SELECT product_id FROM product_categories WHERE category_id = ?)
+	`
+	_, err = db.ExecContext(ctx, query, time.Now().UTC(), categoryID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error updating versions of products in category", "error", err)
+	}
+	return err
+}
+
+// Category deletion policies deciding what happens to products linked to deleted category
+const (
+	// DeleteRestrict refuses to delete category that still has products
+	DeleteRestrict = "restrict"
+	// DeleteCascadeLinks removes links between category and its products,
+	// refusing if some product would be left without any category
+	DeleteCascadeLinks = "cascade-links"
+	// DeleteReassign moves products of deleted category to another category
+	DeleteReassign = "reassign-to"
+)
+
+// DeletePolicy describes how DeleteCategory treats products of deleted category
+type DeletePolicy struct {
+	// Mode is one of DeleteRestrict, DeleteCascadeLinks and DeleteReassign, empty Mode means DeleteRestrict
+	Mode string
+	// ReassignTo is name of category receiving products when Mode is DeleteReassign
+	ReassignTo string
+}
+
 // DeleteCategory deletes specified category from database
-// Takes database connection and name of category to be deleted as parameters
-// Returns error if any occurred during deletion process
-func DeleteCategory(db *sql.DB, categoryName string) error {
-	// Execute DELETE query to delete category from database
-	query := "DELETE FROM categories WHERE name = ?"
-	result, err := db.Exec(query, categoryName)
+// Products linked to category are handled according to deletion policy
+// Subcategories of deleted category are moved up to parent of deleted category,
+// so deleting category never removes or orphans its descendants
+// Category is deleted only if its stored version equals expected version, unless version is AnyVersion
+// Consists of several statements, so callers should run it inside transaction (see WithTx),
+// so that links and children are never changed for category that is not deleted
+// Takes database connection or transaction, name of category to be deleted, deletion policy and expected version as parameters
+// Returns error if any occurred during deletion process, error of kind ErrPreconditionFailed if version differs
+func DeleteCategory(ctx context.Context, db Executor, categoryName string, policy DeletePolicy, version int64) error {
+	// Check if category exists in database
+	categoryID, err := GetCategoryID(ctx, db, categoryName)
 	if err != nil {
-		logger.Println("Error deleting category from database:", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			logger.FromContext(ctx).Info("Category not found", "category", categoryName)
+			return categoryNotFoundError(categoryName, err)
+		}
 		return err
 	}
 
-	// Get number of rows affected by DELETE operation
-	rowsAffected, err := result.RowsAffected()
+	// Check that category was not changed since client read it
+	if err := checkCategoryVersion(ctx, db, categoryID, categoryName, version); err != nil {
+		return err
+	}
+
+	// Handle products linked to category
+	if err := releaseCategoryProducts(ctx, db, categoryID, policy); err != nil {
+		logger.FromContext(ctx).Info("Cannot delete category", "category", categoryName, "policy", policy.Mode, "error", err)
+		return err
+	}
+
+	// Move subcategories to parent of deleted category
+	query := `
+		UPDATE categories
+		SET parent_id = (SELECT parent_id FROM categories WHERE id = ?), version = version + 1
+		WHERE parent_id = ?
+	`
+	_, err = db.ExecContext(ctx, query, categoryID, categoryID)
 	if err != nil {
-		logger.Println("Error getting rows affected:", err)
-		// Return error indicating that no rows were affected
+		logger.FromContext(ctx).Error("Error moving subcategories of deleted category", "error", err)
+		return err
+	}
+
+	// Execute DELETE query to delete category from database
+	query = "DELETE FROM categories WHERE id = ?"
+	_, err = db.ExecContext(ctx, query, categoryID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error deleting category from database", "error", err)
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Category deleted", "category", categoryName, "policy", policy.Mode)
+	return nil
+}
+
+// releaseCategoryProducts removes links between category and its products according to deletion policy
+// Returns error if policy does not allow category to be deleted
+func releaseCategoryProducts(ctx context.Context, tx Executor, categoryID int64, policy DeletePolicy) error {
+	switch policy.Mode {
+	case "", DeleteRestrict:
+		// Refuse deletion if category still has products
+		var count int
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM product_categories WHERE category_id = ?", categoryID).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ConflictError(CodeCategoryNotEmpty, "category has %d products, use policy %s or %s",
+				count, DeleteCascadeLinks, DeleteReassign)
+		}
+		return nil
+
+	case DeleteCascadeLinks:
+		// Find products for which this category is the only one
+		orphans, err := getProductsOnlyInCategory(ctx, tx, categoryID)
+		if err != nil {
+			return err
+		}
+		if len(orphans) > 0 {
+			return ConflictError(CodeCategoryNotEmpty, "products %s would be left without category, use policy %s",
+				strings.Join(orphans, ", "), DeleteReassign)
+		}
+
+	case DeleteReassign:
+		// Resolve category receiving products
+		if policy.ReassignTo == "" {
+			return ValidationError(CodeInvalidDeletePolicy, "target category is required for policy %s", DeleteReassign)
+		}
+		targetID, err := GetCategoryID(ctx, tx, policy.ReassignTo)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ValidationError(CodeTargetNotFound, "target category %q not found", policy.ReassignTo).Wrap(err)
+			}
+			return err
+		}
+		if targetID == categoryID {
+			return ValidationError(CodeInvalidDeletePolicy, "target category must differ from deleted category")
+		}
+
+		// Link products to target category, skipping products already linked to it
+		query := `
+			INSERT OR IGNORE INTO product_categories (product_id, category_id)
+			SELECT product_id, ? FROM product_categories WHERE category_id = ?
+		`
+		if _, err := tx.ExecContext(ctx, query, targetID, categoryID); err != nil {
+			logger.FromContext(ctx).Error("Error reassigning products to target category", "error", err)
+			return err
+		}
+
+	default:
+		return ValidationError(CodeInvalidDeletePolicy, "unknown deletion policy %q", policy.Mode)
+	}
+
+	// Products lose link to deleted category, so they change
+	query := `
+		UPDATE products SET version = version + 1, updated_at = ?
+		WHERE id IN (SELECT product_id FROM product_categories WHERE category_id = ?)
+	`
+	if _, err := tx.ExecContext(ctx, query, time.Now().UTC(), categoryID); err != nil {
+		logger.FromContext(ctx).Error("Error updating versions of products in deleted category", "error", err)
 		return err
 	}
 
-	// Check if no rows were affected by DELETE operation
+	// Remove links of deleted category
+	_, err := tx.ExecContext(ctx, "DELETE FROM product_categories WHERE category_id = ?", categoryID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error deleting category associations from database", "error", err)
+	}
+	return err
+}
+
+// getProductsOnlyInCategory retrieves names of products linked to specified category and to no other category
+func getProductsOnlyInCategory(ctx context.Context, db Executor, categoryID int64) ([]string, error) {
+	query := `
+		SELECT p.name
+		FROM product_categories pc
+		JOIN products p ON p.id = pc.product_id
+		WHERE pc.category_id = ? AND NOT EXISTS (
+			SELECT 1 FROM product_categories other
+			WHERE other.product_id = pc.product_id AND other.category_id <> pc.category_id
+		)
+		ORDER BY p.name
+	`
+	rows, err := db.QueryContext(ctx, query, categoryID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error executing database query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			logger.FromContext(ctx).Error("Error scanning row from query result", "error", err)
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// MoveCategory places category with specified name under new parent category
+// Empty parent name makes category top-level category
+// Category is moved only if its stored version equals expected version, unless version is AnyVersion
+// Returns error if either category does not exist or if move would create cycle,
+// that is when new parent is category itself or one of its descendants,
+// and error of kind ErrPreconditionFailed if version differs
+// Returns new version of moved category
+// Consists of several statements, so callers should run it inside transaction (see WithTx)
+func MoveCategory(ctx context.Context, db Executor, categoryName, parentName string, version int64) (int64, error) {
+	// Check if category exists in database
+	categoryID, err := GetCategoryID(ctx, db, categoryName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, categoryNotFoundError(categoryName, err)
+		}
+		return 0, err
+	}
+
+	// Resolve ID of new parent category and make sure it is not inside moved subtree
+	var parentID sql.NullInt64
+	if parentName != "" {
+		id, err := GetCategoryID(ctx, db, parentName)
+		if err != nil {
+			logger.FromContext(ctx).Error("Error getting parent category ID", "error", err)
+			return 0, parentNotFoundError(err)
+		}
+
+		subtree, err := GetSubtreeCategoryIDs(ctx, db, categoryID)
+		if err != nil {
+			return 0, err
+		}
+		for _, subtreeID := range subtree {
+			if subtreeID == id {
+				logger.FromContext(ctx).Info("Cannot move category: cycle detected", "category", categoryName, "parent", parentName)
+				return 0, ConflictError(CodeCategoryCycle, "category cannot be moved under itself or its descendant")
+			}
+		}
+		parentID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	// Execute UPDATE query to set new parent of category, if category still has expected version
+	query := "UPDATE categories SET parent_id = ?, version = version + 1 WHERE id = ? AND " + versionCondition
+	result, err := db.ExecContext(ctx, query, append([]interface{}{parentID, categoryID}, versionArgs(version)...)...)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error moving category in database", "error", err)
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Error getting rows affected", "error", err)
+		return 0, err
+	}
+	if rowsAffected == 0 && version != AnyVersion {
+		logger.FromContext(ctx).Info("Category version is outdated", "category", categoryName, "version", version)
+		return 0, versionMismatchError("category", categoryName)
+	}
 	if rowsAffected == 0 {
-		logger.Printf("Category %s not found in database", categoryName)
-		return errors.New("no rows affected, category not found")
+		return 0, categoryNotFoundError(categoryName, sql.ErrNoRows)
+	}
+
+	// Read version assigned by move
+	var newVersion int64
+	err = db.QueryRowContext(ctx, "SELECT version FROM categories WHERE id = ?", categoryID).Scan(&newVersion)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting category version", "error", err)
+		return 0, err
 	}
 
-	logger.Println("Category deleted successfully")
-	// Return nil to indicate success and no error
+	logger.FromContext(ctx).Info("Category moved", "category", categoryName, "parent", parentName)
+	return newVersion, nil
+}
+
+// GetSubtreeCategoryIDs retrieves IDs of category with specified ID and all of its descendants
+// takes database connection and ID of root category of subtree as parameters
+// returns slice of category IDs and any error encountered
+func GetSubtreeCategoryIDs(ctx context.Context, db Executor, categoryID int64) ([]int64, error) {
+	// UNION (not UNION ALL) stops recursion even if data already contains cycle
+	query := `
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT id FROM subtree
+	`
+	rows, err := db.QueryContext(ctx, query, categoryID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error querying category subtree", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Collect IDs of all categories in subtree
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			logger.FromContext(ctx).Error("Error scanning category subtree row", "error", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Error iterating through category subtree rows", "error", err)
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// GetCategoryTree retrieves all categories from database arranged as tree
+// Returns slice of top-level categories, each containing its subcategories, and any error encountered
+func GetCategoryTree(ctx context.Context, db *sql.DB) ([]*CategoryNode, error) {
+	// Execute SELECT query to retrieve all categories with their parents
+	query := "SELECT id, name, parent_id FROM categories ORDER BY name"
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error querying categories", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Read all categories into nodes, remembering parent of each node
+	nodes := make(map[int]*CategoryNode)
+	parents := make(map[int]sql.NullInt64)
+	var order []int
+	for rows.Next() {
+		node := &CategoryNode{Children: []*CategoryNode{}}
+		var parentID sql.NullInt64
+		if err := rows.Scan(&node.ID, &node.Name, &parentID); err != nil {
+			logger.FromContext(ctx).Error("Error scanning category row", "error", err)
+			return nil, err
+		}
+		nodes[node.ID] = node
+		parents[node.ID] = parentID
+		order = append(order, node.ID)
+	}
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Error iterating through category rows", "error", err)
+		return nil, err
+	}
+
+	// Attach each node to its parent, categories without existing parent become roots
+	roots := []*CategoryNode{}
+	for _, id := range order {
+		node := nodes[id]
+		parentID := parents[id]
+		if parent, ok := nodes[int(parentID.Int64)]; parentID.Valid && ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	logger.FromContext(ctx).Debug("Got category tree", "count", len(order))
+	return roots, nil
+}
+
+// checkCategoryVersion checks that category with specified ID has expected version, unless version is AnyVersion
+func checkCategoryVersion(ctx context.Context, db Executor, categoryID int64, categoryName string, version int64) error {
+	if version == AnyVersion {
+		return nil
+	}
+	var current int64
+	if err := db.QueryRowContext(ctx, "SELECT version FROM categories WHERE id = ?", categoryID).Scan(&current); err != nil {
+		logger.FromContext(ctx).Error("Error selecting category version", "error", err)
+		return err
+	}
+	if current != version {
+		logger.FromContext(ctx).Info("Category version is outdated", "category", categoryName, "version", version, "current", current)
+		return versionMismatchError("category", categoryName)
+	}
 	return nil
 }
+
+// parentNotFoundError converts error of parent category lookup into descriptive error
+func parentNotFoundError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ValidationError(CodeParentNotFound, "parent category not found").Wrap(err)
+	}
+	return err
+}
+
+// categoryNotFoundError creates error reporting that category with specified name does not exist
+func categoryNotFoundError(categoryName string, err error) error {
+	return NotFoundError(CodeCategoryNotFound, "category %q not found", categoryName).Wrap(err)
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"time"
+)
+
+// Statuses of collection run
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// DefaultCollectorRunsLimit and MaxCollectorRunsLimit bound number of runs returned by GetCollectorRuns
+const (
+	DefaultCollectorRunsLimit = 20
+	MaxCollectorRunsLimit     = 100
+)
+
+// CollectorRun is single collection of products from one source
+type CollectorRun struct {
+	ID         int64      `json:"id"`
+	Source     string     `json:"source"`
+	Status     string     `json:"status"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Inserted   int        `json:"inserted"`
+	Updated    int        `json:"updated"`
+	Unchanged  int        `json:"unchanged"`
+	Removed    int        `json:"removed"`
+	Failed     int        `json:"failed"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// collectorRunColumns lists columns of 'collector_runs' table in order expected by scanCollectorRun
+const collectorRunColumns = "id, source, status, started_at, finished_at, inserted, updated, unchanged, removed, failed, error"
+
+// StartCollectorRun records start of collection from specified source
+// returns recorded run with status RunRunning and any error encountered
+func StartCollectorRun(ctx context.Context, db Executor, source string) (*CollectorRun, error) {
+	run := &CollectorRun{Source: source, Status: RunRunning, StartedAt: time.Now().UTC()}
+
+	result, err := db.ExecContext(ctx, "INSERT INTO collector_runs (source, status, started_at) VALUES (?, ?, ?)",
+		run.Source, run.Status, run.StartedAt)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error inserting collector run", "error", err)
+		return nil, err
+	}
+	run.ID, err = result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// FinishCollectorRun records counts and end of collection run
+// Run becomes RunFailed if runErr is not nil and RunSucceeded otherwise
+// takes database connection, run with filled counts and error of run as parameters
+func FinishCollectorRun(ctx context.Context, db Executor, run *CollectorRun, runErr error) error {
+	finishedAt := time.Now().UTC()
+	run.FinishedAt = &finishedAt
+	run.Status = RunSucceeded
+	run.Error = ""
+	if runErr != nil {
+		run.Status = RunFailed
+		run.Error = runErr.Error()
+	}
+
+	query := `
+		UPDATE collector_runs SET status = ?, finished_at = ?, inserted = ?, updated = ?, unchanged = ?,
+			removed = ?, failed = ?, error = ?
+		WHERE id = ?
+	`
+	_, err := db.ExecContext(ctx, query, run.Status, finishedAt, run.Inserted, run.Updated, run.Unchanged,
+		run.Removed, run.Failed, nullableString(run.Error), run.ID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error updating collector run", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// AbortUnfinishedCollectorRuns marks runs left running by previous process as failed
+// returns number of aborted runs and any error encountered
+func AbortUnfinishedCollectorRuns(ctx context.Context, db Executor) (int64, error) {
+	result, err := db.ExecContext(ctx, "UPDATE collector_runs SET status = ?, finished_at = ?, error = ? WHERE status = ?",
+		RunFailed, time.Now().UTC(), "collection was interrupted", RunRunning)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error aborting unfinished collector runs", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetCollectorRuns retrieves most recent collection runs, newest first
+// Non-positive limit returns DefaultCollectorRunsLimit runs, limit is capped at MaxCollectorRunsLimit
+func GetCollectorRuns(ctx context.Context, db Executor, limit int) ([]CollectorRun, error) {
+	if limit <= 0 {
+		limit = DefaultCollectorRunsLimit
+	}
+	if limit > MaxCollectorRunsLimit {
+		limit = MaxCollectorRunsLimit
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT "+collectorRunColumns+" FROM collector_runs ORDER BY id DESC LIMIT ?", limit)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting collector runs", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []CollectorRun{}
+	for rows.Next() {
+		run, err := scanCollectorRun(rows)
+		if err != nil {
+			logger.FromContext(ctx).Error("Error scanning collector run", "error", err)
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// GetLastCollectorRun retrieves most recent collection run
+// returns nil run without error if collector has never run
+func GetLastCollectorRun(ctx context.Context, db Executor) (*CollectorRun, error) {
+	row := db.QueryRowContext(ctx, "SELECT "+collectorRunColumns+" FROM collector_runs ORDER BY id DESC LIMIT 1")
+	run, err := scanCollectorRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting last collector run", "error", err)
+		return nil, err
+	}
+	return &run, nil
+}
+
+// scanCollectorRun scans single run selected with collectorRunColumns from provided row
+func scanCollectorRun(row rowScanner) (CollectorRun, error) {
+	var run CollectorRun
+	var finishedAt sql.NullTime
+	var runErr sql.NullString
+
+	err := row.Scan(&run.ID, &run.Source, &run.Status, &run.StartedAt, &finishedAt, &run.Inserted, &run.Updated,
+		&run.Unchanged, &run.Removed, &run.Failed, &runErr)
+	if err != nil {
+		return CollectorRun{}, err
+	}
+
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	run.Error = runErr.String
+	return run, nil
+}
+
+// DeleteCollectorRunsBefore deletes finished collection runs started before specified time
+// returns number of deleted runs and any error encountered
+func DeleteCollectorRunsBefore(ctx context.Context, db Executor, before time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, "DELETE FROM collector_runs WHERE started_at < ? AND status != ?", before.UTC(), RunRunning)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error deleting old collector runs", "error", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
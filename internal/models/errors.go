@@ -0,0 +1,168 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Kinds of domain errors, error of any kind can be detected with errors.Is
+var (
+	// ErrNotFound means that requested resource does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means that request conflicts with current state of resource
+	ErrConflict = errors.New("conflict")
+	// ErrValidation means that request contains invalid data
+	ErrValidation = errors.New("validation failed")
+	// ErrUnauthorized means that user is not authenticated
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden means that user is authenticated, but role of user does not grant access
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnavailable means that requested feature is temporarily or permanently not available
+	ErrUnavailable = errors.New("unavailable")
+	// ErrMalformed means that request body or parameters cannot be decoded
+	ErrMalformed = errors.New("malformed request")
+	// ErrTooLarge means that request body exceeds size limit
+	ErrTooLarge = errors.New("request too large")
+	// ErrPreconditionFailed means that resource was changed since client read it
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPreconditionRequired means that request must name version of resource it changes
+	ErrPreconditionRequired = errors.New("precondition required")
+	// ErrUnsupportedMediaType means that request body has format not accepted by requested resource
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	// ErrMethodNotAllowed means that requested resource does not support method of request
+	ErrMethodNotAllowed = errors.New("method not allowed")
+	// ErrInternal means that request failed because of fault of server, such as panic in handler
+	ErrInternal = errors.New("internal error")
+)
+
+// Stable error codes reported to clients, clients may switch on them, so codes must never change
+const (
+	CodeProductNotFound      = "product_not_found"
+	CodeCategoryNotFound     = "category_not_found"
+	CodeUserNotFound         = "user_not_found"
+	CodeProductExists        = "product_exists"
+	CodeSKUExists            = "sku_exists"
+	CodeCategoryExists       = "category_exists"
+	CodeUsernameTaken        = "username_taken"
+	CodeCategoryNotEmpty     = "category_not_empty"
+	CodeCategoryCycle        = "category_cycle"
+	CodeParentNotFound       = "parent_category_not_found"
+	CodeTargetNotFound       = "target_category_not_found"
+	CodeCategoryRequired     = "category_required"
+	CodeReservedCategoryName = "reserved_category_name"
+	CodeInvalidProduct       = "invalid_product"
+	CodeInvalidPrice         = "invalid_price"
+	CodeInvalidCurrency      = "invalid_currency"
+	CodeInvalidDeletePolicy  = "invalid_delete_policy"
+	CodeInvalidSort          = "invalid_sort"
+	CodeInvalidPagination    = "invalid_pagination"
+	CodeInvalidParameter     = "invalid_parameter"
+	CodeInvalidSearchQuery   = "invalid_search_query"
+	CodeInvalidRole          = "invalid_role"
+	CodeInvalidCredentials   = "invalid_credentials"
+	CodeInvalidToken         = "invalid_token"
+	CodeTokenExpired         = "token_expired"
+	CodeMissingAuthorization = "missing_authorization"
+	CodeForbidden            = "forbidden"
+	CodeRouteNotFound        = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeSearchUnavailable    = "search_unavailable"
+	CodeCollectionInProgress = "collection_in_progress"
+	CodeCollectorUnavailable = "collector_unavailable"
+	CodeValidationFailed     = "validation_failed"
+	CodeMalformedBody        = "malformed_body"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeVersionMismatch      = "version_mismatch"
+	CodeVersionRequired      = "version_required"
+	CodeInvalidVersion       = "invalid_version"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeInvalidPatch         = "invalid_patch"
+	CodePatchConflict        = "patch_conflict"
+	CodeInternalError        = "internal_error"
+)
+
+// Violation describes single invalid field of request
+type Violation struct {
+	// Field is path of field in request body, for example "Categories[0].name"
+	Field string `json:"field"`
+	// Code is stable code of violated rule, for example "required" or "too_long"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is domain error of specified kind carrying stable code and message understandable by clients
+type Error struct {
+	// Kind is one of error kinds, such as ErrNotFound or ErrValidation
+	Kind error
+	// Code is one of Code constants
+	Code string
+	// Message describes error to client
+	Message string
+	// Err is underlying error, if any
+	Err error
+	// Violations lists invalid fields of request, if error is caused by them
+	Violations []Violation
+}
+
+// Error returns message of error
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether error is of target kind
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+// Unwrap returns underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundError creates error of kind ErrNotFound with message formatted according to format specifier
+func NotFoundError(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: ErrNotFound, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ConflictError creates error of kind ErrConflict with message formatted according to format specifier
+func ConflictError(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: ErrConflict, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidationError creates error of kind ErrValidation with message formatted according to format specifier
+func ValidationError(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: ErrValidation, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// UnauthorizedError creates error of kind ErrUnauthorized with message formatted according to format specifier
+func UnauthorizedError(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: ErrUnauthorized, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// ForbiddenError creates error of kind ErrForbidden with message formatted according to format specifier
+func ForbiddenError(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: ErrForbidden, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// MalformedError creates error of kind ErrMalformed with message formatted according to format specifier
+func MalformedError(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: ErrMalformed, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// PreconditionFailedError creates error of kind ErrPreconditionFailed with message formatted according to format specifier
+func PreconditionFailedError(code, format string, args ...interface{}) *Error {
+	return &Error{Kind: ErrPreconditionFailed, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap sets underlying error of error and returns error
+func (e *Error) Wrap(err error) *Error {
+	e.Err = err
+	return e
+}
+
+// isUniqueViolation checks if error is violation of UNIQUE constraint
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
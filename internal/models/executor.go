@@ -0,0 +1,53 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+)
+
+// Executor is implemented by both *sql.DB and *sql.Tx
+// Model functions accepting Executor can run either directly on database or as part of transaction
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// WithTx runs fn inside single transaction
+// Commits transaction if fn succeeds and rolls it back if fn returns error or panics,
+// so changes made by fn are either all applied or not applied at all
+// Transaction is rolled back as well if ctx is cancelled before it is committed
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	// Begin transaction
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error beginning transaction", "error", err)
+		return err
+	}
+
+	// Roll back transaction if fn panics and propagate panic
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	// Roll back transaction if fn fails
+	if err = fn(tx); err != nil {
+		// Transaction is already rolled back if ctx was cancelled
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			logger.FromContext(ctx).Error("Error rolling back transaction", "error", rollbackErr)
+		}
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		logger.FromContext(ctx).Error("Error committing transaction", "error", err)
+		return err
+	}
+	return nil
+}
@@ -1,107 +1,256 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"regexp"
+	"strings"
+	"time"
 )
 
+// DefaultCurrency is currency assigned to product price when none is specified
+const DefaultCurrency = "USD"
+
+const (
+	// DefaultProductsLimit is page size used when listing products without explicit limit
+	DefaultProductsLimit = 20
+	// MaxProductsLimit is largest page size allowed when listing products
+	MaxProductsLimit = 100
+)
+
+// productSortColumns maps sort keys accepted by GetProducts to columns of 'products' table
+var productSortColumns = map[string]string{
+	"id":         "p.id",
+	"name":       "p.name",
+	"price":      "p.price",
+	"created_at": "p.created_at",
+	"updated_at": "p.updated_at",
+}
+
+// currencyPattern matches ISO 4217 alphabetic currency codes
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
 type Product struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Price       int64     `json:"price"`
+	Currency    string    `json:"currency"`
+	Description string    `json:"description"`
+	SKU         string    `json:"sku"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Source and ExternalID identify product in external system it was collected from
+	Source     string `json:"source,omitempty"`
+	ExternalID string `json:"external_id,omitempty"`
+	// DiscontinuedAt is set when collected product disappears from its source
+	DiscontinuedAt *time.Time `json:"discontinued_at,omitempty"`
+	// Version grows with every change of product or of its categories
+	// UpdateProduct changes product only if stored version equals Version, unless Version is AnyVersion
+	Version int64 `json:"version"`
+}
+
+// ProductDetails represents single product together with names of its categories
+type ProductDetails struct {
+	Product
+	Categories []string `json:"categories"`
+}
+
+// ProductListOptions holds pagination and sorting options for GetProducts
+type ProductListOptions struct {
+	Limit  int
+	Offset int
+	// Sort is one of keys of productSortColumns, products are sorted by ID if empty
+	Sort string
+	// Desc reverses sort order
+	Desc bool
 }
 
 // AddProduct inserts new product into 'products' table in database and associates with specified categories
-// takes database connection, pointer to product information, and slice of categories as parameters
+// Consists of several statements, so callers should run it inside transaction (see WithTx)
+// takes database connection or transaction, pointer to product information, and slice of categories as parameters
 // returns error if any occurred during insertion process
-func AddProduct(db *sql.DB, product *Product, categories []Category) error {
+func AddProduct(ctx context.Context, db Executor, product *Product, categories []Category) error {
 	// If product does not have any categories, return error
 	if len(categories) == 0 {
-		logger.Println("Error adding product: product must have at least one category")
-		return errors.New("product must have at least one category")
+		logger.FromContext(ctx).Info("Cannot add product without categories", "product", product.Name)
+		return ValidationError(CodeCategoryRequired, "product must have at least one category")
 	}
 
+	// Check price and currency of product
+	if err := validateProductPrice(product); err != nil {
+		logger.FromContext(ctx).Info("Cannot add product", "product", product.Name, "error", err)
+		return err
+	}
+
+	// Set creation and modification time of product
+	now := time.Now().UTC()
+	product.CreatedAt = now
+	product.UpdatedAt = now
+
 	// Insert product into 'products' table
-	query := "INSERT INTO products (name) VALUES (?)"
-	result, err := db.Exec(query, product.Name)
+	query := `
+		INSERT INTO products (name, price, currency, description, sku, created_at, updated_at, source, external_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	result, err := db.ExecContext(ctx, query, product.Name, product.Price, product.Currency, product.Description,
+		nullableString(product.SKU), product.CreatedAt, product.UpdatedAt,
+		nullableString(product.Source), nullableString(product.ExternalID))
+	if isUniqueViolation(err) {
+		logger.FromContext(ctx).Info("Product already exists", "product", product.Name, "error", err)
+		return productExistsError(product, err)
+	}
 	if err != nil {
-		logger.Println("Error inserting product into database:", err)
+		logger.FromContext(ctx).Error("Error inserting product into database", "error", err)
 		return err
 	}
 
 	productID, err := result.LastInsertId()
 	if err != nil {
-		logger.Println("Error getting last insert ID:", err)
+		logger.FromContext(ctx).Error("Error getting last insert ID", "error", err)
 		return err
 	}
+	product.ID = int(productID)
+	product.Version = 1
 
-	// Insert associations into 'product_categories' table
-	for _, category := range categories {
-		categoryID, err := GetCategoryID(db, category.Name)
+	// Insert associations into 'product_categories' table, once per category name
+	for _, category := range uniqueCategories(categories) {
+		categoryID, err := GetCategoryID(ctx, db, category.Name)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 
 				// If category doesn't exist, add to 'categories' table
-				categoryID, err = AddCategory(db, &category)
+				categoryID, err = AddCategory(ctx, db, &category)
 				if err != nil {
-					logger.Println("Error adding category:", err)
+					logger.FromContext(ctx).Error("Error adding category", "error", err)
 					return err
 				}
 			} else {
-				logger.Println("Error getting category ID:", err)
+				logger.FromContext(ctx).Error("Error getting category ID", "error", err)
 				return err
 			}
 		}
 		// Insert association into 'product_categories' table
 		query = "INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)"
-		_, err = db.Exec(query, productID, categoryID)
+		_, err = db.ExecContext(ctx, query, productID, categoryID)
 		if err != nil {
-			logger.Println("Error inserting association into product_categories:", err)
+			logger.FromContext(ctx).Error("Error inserting association into product_categories", "error", err)
 			return err
 		}
 	}
 
-	logger.Println("Product added successfully")
+	logger.FromContext(ctx).Info("Product added", "product", product.Name, "id", product.ID)
 	return nil
 }
 
 // UpdateProduct edits existing product in database along with its associated categories
-// takes database connection, pointer to updated product information, and slice of updated categories as parameters
-// returns error if any occurred during update process
-func UpdateProduct(db *sql.DB, product *Product, categories []Category) error {
+// Product is found by product.ID and renamed to product.Name, or found by product.Name if ID is not set
+// Product is changed only if its stored version equals product.Version, which is then set to new version
+// Consists of several statements, so callers should run it inside transaction (see WithTx)
+// takes database connection or transaction, pointer to updated product information, and slice of updated categories as parameters
+// returns error if any occurred during update process, error of kind ErrPreconditionFailed if version differs
+func UpdateProduct(ctx context.Context, db Executor, product *Product, categories []Category) error {
 	// If product does not have any categories, return error
 	if len(categories) == 0 {
-		logger.Println("Error adding product: product must have at least one category")
-		return errors.New("product must have at least one category")
+		logger.FromContext(ctx).Info("Cannot update product without categories", "product", product.Name)
+		return ValidationError(CodeCategoryRequired, "product must have at least one category")
 	}
 
-	// Check if product exists in database
-	productID, err := GetProductID(db, product.Name)
+	// Check price and currency of product
+	if err := validateProductPrice(product); err != nil {
+		logger.FromContext(ctx).Info("Cannot update product", "product", product.Name, "error", err)
+		return err
+	}
+
+	// Check if product exists in database, unless it is identified by ID
+	productID := int64(product.ID)
+	if productID == 0 {
+		var err error
+		productID, err = GetProductID(ctx, db, product.Name)
+		if err != nil {
+			return productNotFoundError(product.Name, err)
+		}
+	} else if err := db.QueryRowContext(ctx, "SELECT id FROM products WHERE id = ?", productID).Scan(&productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NotFoundError(CodeProductNotFound, "product %d not found", productID).Wrap(err)
+		}
+		logger.FromContext(ctx).Error("Error selecting product", "id", productID, "error", err)
+		return err
+	}
+
+	// Update product attributes in 'products' table, if product still has expected version
+	product.UpdatedAt = time.Now().UTC()
+	query := `
+		UPDATE products SET name = ?, price = ?, currency = ?, description = ?, sku = ?, updated_at = ?, version = version + 1
+		WHERE id = ? AND ` + versionCondition
+	args := append([]interface{}{product.Name, product.Price, product.Currency, product.Description,
+		nullableString(product.SKU), product.UpdatedAt, productID}, versionArgs(product.Version)...)
+	result, err := db.ExecContext(ctx, query, args...)
+	if isUniqueViolation(err) {
+		logger.FromContext(ctx).Info("Product already exists", "product", product.Name, "error", err)
+		return productExistsError(product, err)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Error updating product in database", "error", err)
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
+		logger.FromContext(ctx).Error("Error getting rows affected", "error", err)
 		return err
 	}
+	if rowsAffected == 0 {
+		logger.FromContext(ctx).Info("Product version is outdated", "product", product.Name, "version", product.Version)
+		return versionMismatchError("product", product.Name)
+	}
+	product.ID = int(productID)
 
+	// Replace categories associated with product
+	if err := SetProductCategories(ctx, db, productID, categories); err != nil {
+		return err
+	}
+
+	// Read version assigned by update
+	err = db.QueryRowContext(ctx, "SELECT version FROM products WHERE id = ?", productID).Scan(&product.Version)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting product version", "error", err)
+		return err
+	}
+
+	logger.FromContext(ctx).Info("Product updated", "product", product.Name, "id", product.ID)
+	return nil
+}
+
+// SetProductCategories replaces categories associated with product by specified categories
+// Categories that do not exist yet are created, associations with other categories are removed
+// takes database connection or transaction, product ID, and slice of categories as parameters
+// returns error if any occurred during update process
+func SetProductCategories(ctx context.Context, db Executor, productID int64, categories []Category) error {
 	// Get current categories associated with product
-	currentCategories, err := GetCategoriesByProductID(db, productID)
+	currentCategories, err := GetCategoriesByProductID(ctx, db, productID)
 	if err != nil {
 		return err
 	}
 
+	// Repeated category names are associated only once
+	categories = uniqueCategories(categories)
+
 	// Map to store category names to their IDs
 	categoryIDMap := make(map[string]int64)
 
 	// Insert new categories into 'categories' table if they don't exist yet
 	for _, category := range categories {
-		categoryID, err := GetCategoryID(db, category.Name)
+		categoryID, err := GetCategoryID(ctx, db, category.Name)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				// If category doesn't exist, add to 'categories' table
-				categoryID, err = AddCategory(db, &category)
+				categoryID, err = AddCategory(ctx, db, &category)
 				if err != nil {
 					return err
 				}
 			} else {
-				logger.Println("Error getting category ID:", err)
+				logger.FromContext(ctx).Error("Error getting category ID", "error", err)
 				return err
 			}
 		}
@@ -111,7 +260,7 @@ func UpdateProduct(db *sql.DB, product *Product, categories []Category) error {
 	// Remove categories that are no longer associated with product
 	for _, currentCategory := range currentCategories {
 		if _, exists := categoryIDMap[currentCategory]; !exists {
-			if err := DeleteProductCategory(db, productID, currentCategory); err != nil {
+			if err := DeleteProductCategory(ctx, db, productID, currentCategory); err != nil {
 				return err
 			}
 		}
@@ -122,102 +271,271 @@ func UpdateProduct(db *sql.DB, product *Product, categories []Category) error {
 		categoryID := categoryIDMap[category.Name]
 		if !Contains(currentCategories, category.Name) {
 			query := "INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)"
-			_, err = db.Exec(query, productID, categoryID)
+			_, err = db.ExecContext(ctx, query, productID, categoryID)
 			if err != nil {
-				logger.Println("Error inserting association into product_categories:", err)
+				logger.FromContext(ctx).Error("Error inserting association into product_categories", "error", err)
 				return err
 			}
 		}
 	}
 
-	logger.Println("Product updated successfully")
 	return nil
 }
 
 // DeleteProduct deletes specified product from database along with its associated records in 'product_categories' table
-// takes database connection and name of product to be deleted as parameters
-// returns error if any occurred during deletion process
-func DeleteProduct(db *sql.DB, productName string) error {
+// Product is deleted only if its stored version equals expected version, unless version is AnyVersion
+// Consists of several statements, so callers should run it inside transaction (see WithTx)
+// takes database connection or transaction, name of product to be deleted and its expected version as parameters
+// returns error if any occurred during deletion process, error of kind ErrPreconditionFailed if version differs
+func DeleteProduct(ctx context.Context, db Executor, productName string, version int64) error {
 
 	// Check if product exists in database
-	productID, err := GetProductID(db, productName)
+	productID, err := GetProductID(ctx, db, productName)
 	if err != nil {
-		return err
+		return productNotFoundError(productName, err)
 	}
 
-	// Delete product from 'products' table
-	query := "DELETE FROM products WHERE id = ?"
-	result, err := db.Exec(query, productID)
+	// Delete product from 'products' table, if product still has expected version
+	query := "DELETE FROM products WHERE id = ? AND " + versionCondition
+	result, err := db.ExecContext(ctx, query, append([]interface{}{productID}, versionArgs(version)...)...)
 	if err != nil {
-		logger.Println("Error deleting product from database:", err)
+		logger.FromContext(ctx).Error("Error deleting product from database", "error", err)
 		return err
 	}
 
 	// Get number of rows affected by delete operation
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
-		logger.Println("Error getting rows affected:", err)
+		logger.FromContext(ctx).Error("Error getting rows affected", "error", err)
 		return err
 	}
 
-	// If no rows affected, log message indicating that product was not found in database
+	// If no rows affected, product was changed or deleted since it was found
+	if rowsAffected == 0 && version != AnyVersion {
+		logger.FromContext(ctx).Info("Product version is outdated", "product", productName, "version", version)
+		return versionMismatchError("product", productName)
+	}
 	if rowsAffected == 0 {
-		logger.Printf("Product %s not found in database", productName)
-		return errors.New("no rows affected, product not found")
+		logger.FromContext(ctx).Info("Product not found", "product", productName)
+		return productNotFoundError(productName, sql.ErrNoRows)
 	}
 
 	// Delete associated records from 'product_categories' table
 	query = "DELETE FROM product_categories WHERE product_id = ?"
-	_, err = db.Exec(query, productID)
+	_, err = db.ExecContext(ctx, query, productID)
 	if err != nil {
-		logger.Println("Error deleting product associations from database:", err)
+		logger.FromContext(ctx).Error("Error deleting product associations from database", "error", err)
 		return err
 	}
 
-	logger.Println("Product deleted successfully")
+	logger.FromContext(ctx).Info("Product deleted", "product", productName, "id", productID)
 	return nil
 }
 
-// GetProductsByCategory retrieves names of products belonging to specified category from database
-// takes database connection and name of category as parameters
-// returns slice of strings containing names of products in specified category and any error encountered
-func GetProductsByCategory(db *sql.DB, categoryName string) ([]string, error) {
+// DeleteProductByID deletes product with specified ID like DeleteProduct
+// Consists of several statements, so callers should run it inside transaction (see WithTx)
+// returns error of kind ErrNotFound if product does not exist, error of kind ErrPreconditionFailed if version differs
+func DeleteProductByID(ctx context.Context, db Executor, productID int64, version int64) error {
+	var productName string
+	err := db.QueryRowContext(ctx, "SELECT name FROM products WHERE id = ?", productID).Scan(&productName)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Info("Product not found", "id", productID)
+		return NotFoundError(CodeProductNotFound, "product %d not found", productID).Wrap(err)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting product", "id", productID, "error", err)
+		return err
+	}
+	return DeleteProduct(ctx, db, productName, version)
+}
+
+// GetProductsByCategory retrieves products belonging to specified category from database
+// If recursive is true, products of all descendants of category are included as well
+// takes database connection, name of category and recursive flag as parameters
+// returns slice of products in specified category and any error encountered
+func GetProductsByCategory(ctx context.Context, db Executor, categoryName string, recursive bool) ([]Product, error) {
 	query := `
-        SELECT p.name
+        SELECT ` + productColumns + `
         FROM products p
         JOIN product_categories pc ON p.id = pc.product_id
         JOIN categories c ON pc.category_id = c.id
         WHERE c.name = ?
     `
+	if recursive {
+		query = `
+        WITH RECURSIVE subtree(id) AS (
+            SELECT id FROM categories WHERE name = ?
+            UNION
+            SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+        )
+        SELECT DISTINCT ` + productColumns + `
+        FROM products p
+        JOIN product_categories pc ON p.id = pc.product_id
+        WHERE pc.category_id IN (SELECT id FROM subtree)
+    `
+	}
 
 	// Execute database query to retrieve products in specified category
-	rows, err := db.Query(query, categoryName)
+	rows, err := db.QueryContext(ctx, query, categoryName)
 	if err != nil {
-		logger.Println("Error executing database query:", err)
+		logger.FromContext(ctx).Error("Error executing database query", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	// Initialize slice to store names of products in category
-	var products []string
+	// Initialize slice to store products in category
+	var products []Product
 	// Iterate through query result rows
 	for rows.Next() {
-		var product string
-		// Scan product name from current row
-		if err := rows.Scan(&product); err != nil {
-			logger.Println("Error scanning row from query result:", err)
+		// Scan product from current row
+		product, err := scanProduct(rows)
+		if err != nil {
+			logger.FromContext(ctx).Error("Error scanning row from query result", "error", err)
 			return nil, err
 		}
-		// Append product name to products slice
+		// Append product to products slice
 		products = append(products, product)
 	}
 	// Check for any errors encountered during iteration
 	if err := rows.Err(); err != nil {
-		logger.Println("Error processing query result:", err)
+		logger.FromContext(ctx).Error("Error processing query result", "error", err)
 		return nil, err
 	}
 
-	logger.Println("Successfully got products in category", categoryName)
-	// Return slice of product names and nil error, indicating success
+	logger.FromContext(ctx).Debug("Got products in category", "category", categoryName, "recursive", recursive, "count", len(products))
+	// Return slice of products and nil error, indicating success
 	return products, nil
 }
+
+// validateProductPrice checks that product price is not negative and currency is valid ISO 4217 code
+// assigns DefaultCurrency to product if currency is not specified
+func validateProductPrice(product *Product) error {
+	if product.Price < 0 {
+		return ValidationError(CodeInvalidPrice, "product price must not be negative")
+	}
+
+	// Fall back to default currency if none is provided
+	if product.Currency == "" {
+		product.Currency = DefaultCurrency
+	}
+
+	if !currencyPattern.MatchString(product.Currency) {
+		return ValidationError(CodeInvalidCurrency, "product currency must be three-letter ISO 4217 code")
+	}
+
+	return nil
+}
+
+// GetProducts retrieves page of all products from database sorted according to provided options
+// takes database connection and pagination and sorting options as parameters
+// options are updated with page size actually applied
+// returns slice of products on requested page, total number of products and any error encountered
+func GetProducts(ctx context.Context, db Executor, options *ProductListOptions) ([]Product, int, error) {
+	// Check sort key against allowed columns, as column cannot be passed as query argument
+	sortKey := options.Sort
+	if sortKey == "" {
+		sortKey = "id"
+	}
+	sortColumn, ok := productSortColumns[sortKey]
+	if !ok {
+		return nil, 0, ValidationError(CodeInvalidSort, "unsupported sort field %q", options.Sort)
+	}
+	direction := "ASC"
+	if options.Desc {
+		direction = "DESC"
+	}
+
+	// Apply default and maximum page size
+	if options.Limit <= 0 {
+		options.Limit = DefaultProductsLimit
+	}
+	if options.Limit > MaxProductsLimit {
+		options.Limit = MaxProductsLimit
+	}
+	if options.Offset < 0 {
+		return nil, 0, ValidationError(CodeInvalidPagination, "offset must not be negative")
+	}
+
+	// Count all products to let clients know how many pages there are
+	var total int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM products").Scan(&total)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error counting products", "error", err)
+		return nil, 0, err
+	}
+
+	// Sort by ID as well so that pages are stable when sort column has equal values
+	query := "SELECT " + productColumns + " FROM products p ORDER BY " + sortColumn + " " + direction +
+		", p.id " + direction + " LIMIT ? OFFSET ?"
+	rows, err := db.QueryContext(ctx, query, options.Limit, options.Offset)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error executing database query", "error", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	// Initialize slice to store products on page
+	products := []Product{}
+	// Iterate through query result rows
+	for rows.Next() {
+		product, err := scanProduct(rows)
+		if err != nil {
+			logger.FromContext(ctx).Error("Error scanning row from query result", "error", err)
+			return nil, 0, err
+		}
+		products = append(products, product)
+	}
+	// Check for any errors encountered during iteration
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Error processing query result", "error", err)
+		return nil, 0, err
+	}
+
+	logger.FromContext(ctx).Debug("Got products", "count", len(products), "total", total)
+	return products, total, nil
+}
+
+// GetProduct retrieves product with specified ID from database together with names of its categories
+// takes database connection and product ID as parameters
+// returns product details and any error encountered, error of kind ErrNotFound wrapping sql.ErrNoRows if product does not exist
+func GetProduct(ctx context.Context, db Executor, productID int64) (*ProductDetails, error) {
+	// Retrieve product row
+	query := "SELECT " + productColumns + " FROM products p WHERE p.id = ?"
+	product, err := scanProduct(db.QueryRowContext(ctx, query, productID))
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Debug("Product not found", "id", productID)
+		return nil, NotFoundError(CodeProductNotFound, "product %d not found", productID).Wrap(err)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting product", "id", productID, "error", err)
+		return nil, err
+	}
+
+	// Retrieve categories associated with product
+	categories, err := GetCategoriesByProductID(ctx, db, productID)
+	if err != nil {
+		return nil, err
+	}
+	if categories == nil {
+		categories = []string{}
+	}
+
+	logger.FromContext(ctx).Debug("Got product", "product", product.Name, "id", productID)
+	return &ProductDetails{Product: product, Categories: categories}, nil
+}
+
+// productNotFoundError converts error of product lookup by name into descriptive error
+func productNotFoundError(productName string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFoundError(CodeProductNotFound, "product %q not found", productName).Wrap(err)
+	}
+	return err
+}
+
+// productExistsError creates error reporting that product with same name or SKU already exists
+func productExistsError(product *Product, err error) error {
+	if strings.Contains(err.Error(), "products.sku") {
+		return ConflictError(CodeSKUExists, "product with SKU %q already exists", product.SKU).Wrap(err)
+	}
+	return ConflictError(CodeProductExists, "product %q already exists", product.Name).Wrap(err)
+}
@@ -1,25 +1,69 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
 )
 
+// productColumns lists columns of 'products' table (aliased as p) in order expected by scanProduct
+const productColumns = "p.id, p.name, p.price, p.currency, p.description, p.sku, p.created_at, p.updated_at, " +
+	"p.source, p.external_id, p.discontinued_at, p.version"
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProduct scans single product selected with productColumns from provided row
+// returns scanned product and any error encountered
+func scanProduct(row rowScanner) (Product, error) {
+	var product Product
+	var sku, source, externalID sql.NullString
+	var createdAt, updatedAt, discontinuedAt sql.NullTime
+
+	err := row.Scan(&product.ID, &product.Name, &product.Price, &product.Currency, &product.Description,
+		&sku, &createdAt, &updatedAt, &source, &externalID, &discontinuedAt, &product.Version)
+	if err != nil {
+		return Product{}, err
+	}
+
+	// Optional columns stay zero valued when NULL
+	product.SKU = sku.String
+	product.CreatedAt = createdAt.Time
+	product.UpdatedAt = updatedAt.Time
+	product.Source = source.String
+	product.ExternalID = externalID.String
+	if discontinuedAt.Valid {
+		product.DiscontinuedAt = &discontinuedAt.Time
+	}
+
+	return product, nil
+}
+
+// nullableString converts empty string to NULL so that optional unique columns do not collide
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
 // GetCategoryID retrieves ID of category with specified name from database
 // takes database connection and category name as parameters
 // returns category ID and any error encountered
-func GetCategoryID(db *sql.DB, categoryName string) (int64, error) {
+func GetCategoryID(ctx context.Context, db Executor, categoryName string) (int64, error) {
 	// Construct SQL query to select category ID based on category name
 	query := "SELECT id FROM categories WHERE name = ?"
 	// Execute query and retrieve single row result
-	row := db.QueryRow(query, categoryName)
+	row := db.QueryRowContext(ctx, query, categoryName)
 
 	// Initialize variable to store category ID
 	var categoryID int64
 	// Scan category ID from result row into categoryID variable
 	err := row.Scan(&categoryID)
 	if err != nil {
-		logger.Printf("Category %v does not exist in database yet. Creating...", categoryName)
+		logger.FromContext(ctx).Debug("Category not found", "category", categoryName, "error", err)
 		return 0, err
 	}
 
@@ -30,18 +74,18 @@ func GetCategoryID(db *sql.DB, categoryName string) (int64, error) {
 // GetProductID retrieves ID of product with specified name from database
 // takes database connection and product name as parameters
 // returns product ID and any error encountered
-func GetProductID(db *sql.DB, productName string) (int64, error) {
+func GetProductID(ctx context.Context, db Executor, productName string) (int64, error) {
 	// Construct SQL query to select product ID based on product name
 	query := "SELECT id FROM products WHERE name = ?"
 	// Execute query and retrieve single row result
-	row := db.QueryRow(query, productName)
+	row := db.QueryRowContext(ctx, query, productName)
 
 	// Initialize variable to store product ID
 	var productID int64
 	// Scan product ID from result row into productID variable
 	err := row.Scan(&productID)
 	if err != nil {
-		logger.Printf("Product %v does not exist in database.", productName)
+		logger.FromContext(ctx).Debug("Product not found", "product", productName, "error", err)
 		return 0, err
 	}
 
@@ -52,7 +96,7 @@ func GetProductID(db *sql.DB, productName string) (int64, error) {
 // DeleteProductCategory deletes association between specified product and category from database
 // takes database connection, product ID, and category name as parameters
 // returns error if any occurred during deletion process
-func DeleteProductCategory(db *sql.DB, productID int64, categoryName string) error {
+func DeleteProductCategory(ctx context.Context, db Executor, productID int64, categoryName string) error {
 	// Construct SQL query to delete association between product and category
 	query := `
 		DELETE FROM product_categories
@@ -60,20 +104,20 @@ func DeleteProductCategory(db *sql.DB, productID int64, categoryName string) err
 	`
 
 	// Execute delete query with provided product ID and category name
-	_, err := db.Exec(query, productID, categoryName)
+	_, err := db.ExecContext(ctx, query, productID, categoryName)
 	if err != nil {
-		logger.Println("Error deleting product category association from database:", err)
+		logger.FromContext(ctx).Error("Error deleting product category association from database", "error", err)
 		return err
 	}
 
-	logger.Println("Product category association deleted successfully")
+	logger.FromContext(ctx).Debug("Product category association deleted", "product_id", productID, "category", categoryName)
 	return nil
 }
 
 // GetCategoriesByProductID retrieves categories associated with specified product from database
 // takes database connection and product ID as parameters
 // returns slice of category names and any error encountered
-func GetCategoriesByProductID(db *sql.DB, productID int64) ([]string, error) {
+func GetCategoriesByProductID(ctx context.Context, db Executor, productID int64) ([]string, error) {
 	// Construct SQL query to select categories associated with given product ID
 	query := `
 		SELECT c.name
@@ -83,9 +127,9 @@ func GetCategoriesByProductID(db *sql.DB, productID int64) ([]string, error) {
 	`
 
 	// Execute query to retrieve categories associated with product ID
-	rows, err := db.Query(query, productID)
+	rows, err := db.QueryContext(ctx, query, productID)
 	if err != nil {
-		logger.Println("Error executing database query:", err)
+		logger.FromContext(ctx).Error("Error executing database query", "error", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -97,7 +141,7 @@ func GetCategoriesByProductID(db *sql.DB, productID int64) ([]string, error) {
 		var category string
 		// Scan category name from current row
 		if err := rows.Scan(&category); err != nil {
-			logger.Println("Error scanning row from query result:", err)
+			logger.FromContext(ctx).Error("Error scanning row from query result", "error", err)
 			return nil, err
 		}
 		// Append category name to categories slice
@@ -106,7 +150,7 @@ func GetCategoriesByProductID(db *sql.DB, productID int64) ([]string, error) {
 
 	// Check for any errors encountered during iteration
 	if err := rows.Err(); err != nil {
-		logger.Println("Error processing query result:", err)
+		logger.FromContext(ctx).Error("Error processing query result", "error", err)
 		return nil, err
 	}
 
@@ -9,3 +9,18 @@ func Contains(slice []string, str string) bool {
 	}
 	return false
 }
+
+// uniqueCategories returns categories without repeated names, keeping first occurrence of every name
+// Product is associated with category only once, however many times category is listed
+func uniqueCategories(categories []Category) []Category {
+	seen := make(map[string]bool, len(categories))
+	unique := make([]Category, 0, len(categories))
+	for _, category := range categories {
+		if seen[category.Name] {
+			continue
+		}
+		seen[category.Name] = true
+		unique = append(unique, category)
+	}
+	return unique
+}
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"time"
+)
+
+// Outcomes of synchronizing single collected product with database
+const (
+	SyncInserted  = "inserted"
+	SyncUpdated   = "updated"
+	SyncAdopted   = "adopted"
+	SyncUnchanged = "unchanged"
+)
+
+// SyncOptions controls how collected products are matched with stored products
+type SyncOptions struct {
+	// AdoptExisting lets source take over product with same name and no source, such as product created by hand,
+	// adopted product is then discontinued like any other product of source when it disappears from source
+	AdoptExisting bool
+}
+
+// SyncProduct inserts or updates product collected from external source
+// Product is identified by its Source and ExternalID; product with same name and no source is adopted by source
+// only if options allow it, otherwise such product is left alone and collected product cannot be saved
+// Only name and categories of existing product are updated, discontinued product becomes active again
+// takes database connection or transaction, collected product, its categories and sync options as parameters
+// returns outcome of synchronization (SyncInserted, SyncUpdated, SyncAdopted or SyncUnchanged) and any error encountered
+func SyncProduct(ctx context.Context, db Executor, product *Product, categories []Category, options SyncOptions) (string, error) {
+	if product.Source == "" || product.ExternalID == "" {
+		return "", ValidationError(CodeInvalidProduct, "collected product requires source and external ID")
+	}
+	if len(categories) == 0 {
+		return "", ValidationError(CodeCategoryRequired, "product must belong to at least one category")
+	}
+
+	// Find product previously collected from same source
+	existing, err := GetProductByExternalID(ctx, db, product.Source, product.ExternalID)
+	claimed := false
+	if errors.Is(err, sql.ErrNoRows) && options.AdoptExisting {
+		// Adopt product with same name that was created before sources were tracked
+		existing, err = claimProduct(ctx, db, product)
+		claimed = err == nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if err := AddProduct(ctx, db, product, categories); err != nil {
+			return "", err
+		}
+		return SyncInserted, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	product.ID = existing.ID
+
+	// Compare collected state with stored one
+	currentCategories, err := GetCategoriesByProductID(ctx, db, int64(existing.ID))
+	if err != nil {
+		return "", err
+	}
+	if !claimed && existing.Name == product.Name && existing.DiscontinuedAt == nil &&
+		sameCategories(currentCategories, categories) {
+		return SyncUnchanged, nil
+	}
+
+	query := "UPDATE products SET name = ?, discontinued_at = NULL, updated_at = ?, version = version + 1 WHERE id = ?"
+	_, err = db.ExecContext(ctx, query, product.Name, time.Now().UTC(), existing.ID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error updating collected product", "error", err)
+		return "", err
+	}
+	if err := SetProductCategories(ctx, db, int64(existing.ID), categories); err != nil {
+		return "", err
+	}
+
+	if claimed {
+		logger.FromContext(ctx).Info("Product adopted by source", "product", product.Name, "id", existing.ID,
+			"source", product.Source, "external_id", product.ExternalID)
+		return SyncAdopted, nil
+	}
+	logger.FromContext(ctx).Debug("Collected product updated", "product", product.Name, "source", product.Source)
+	return SyncUpdated, nil
+}
+
+// GetProductByExternalID retrieves product collected from specified source with specified external ID
+// returns sql.ErrNoRows if there is no such product
+func GetProductByExternalID(ctx context.Context, db Executor, source, externalID string) (*Product, error) {
+	query := "SELECT " + productColumns + " FROM products p WHERE p.source = ? AND p.external_id = ?"
+	product, err := scanProduct(db.QueryRowContext(ctx, query, source, externalID))
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// DiscontinueMissingProducts marks active products of specified source whose external IDs are not in seen as discontinued
+// Products are kept in database, so they can be restored when they appear in source again
+// returns number of products marked as discontinued and any error encountered
+func DiscontinueMissingProducts(ctx context.Context, db Executor, source string, seen []string) (int64, error) {
+	seenSet := make(map[string]bool, len(seen))
+	for _, externalID := range seen {
+		seenSet[externalID] = true
+	}
+
+	// Find active products of source
+	rows, err := db.QueryContext(ctx, "SELECT id, external_id FROM products WHERE source = ? AND discontinued_at IS NULL", source)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting products of source", "error", err)
+		return 0, err
+	}
+	var missing []int64
+	for rows.Next() {
+		var id int64
+		var externalID string
+		if err := rows.Scan(&id, &externalID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if !seenSet[externalID] {
+			missing = append(missing, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// Mark products missing from source
+	now := time.Now().UTC()
+	for _, id := range missing {
+		_, err := db.ExecContext(ctx, "UPDATE products SET discontinued_at = ?, updated_at = ?, version = version + 1 WHERE id = ?", now, now, id)
+		if err != nil {
+			logger.FromContext(ctx).Error("Error marking product as discontinued", "error", err)
+			return 0, err
+		}
+	}
+
+	return int64(len(missing)), nil
+}
+
+// claimProduct assigns source and external ID of collected product to product with same name and no source
+// returns adopted product or sql.ErrNoRows if there is no product to adopt
+func claimProduct(ctx context.Context, db Executor, product *Product) (*Product, error) {
+	query := "SELECT " + productColumns + " FROM products p WHERE p.name = ? AND p.source IS NULL"
+	existing, err := scanProduct(db.QueryRowContext(ctx, query, product.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.ExecContext(ctx, "UPDATE products SET source = ?, external_id = ?, version = version + 1 WHERE id = ?",
+		product.Source, product.ExternalID, existing.ID)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error claiming product for source", "error", err)
+		return nil, err
+	}
+
+	existing.Source = product.Source
+	existing.ExternalID = product.ExternalID
+	existing.Version++
+	return &existing, nil
+}
+
+// sameCategories reports whether current category names and collected categories hold same set of names
+func sameCategories(current []string, categories []Category) bool {
+	collected := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		collected[category.Name] = true
+	}
+	if len(collected) != len(current) {
+		return false
+	}
+	for _, name := range current {
+		if !collected[name] {
+			return false
+		}
+	}
+	return true
+}
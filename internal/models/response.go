@@ -9,6 +9,22 @@ type TokenResponseMessage struct {
 	Token   string `json:"token"`
 }
 
-type ErrorResponse struct {
-	Error string `json:"error"`
+// Problem is error response in format of RFC 7807 problem details
+// Code is stable machine-readable code of error, see Code constants
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Code     string `json:"code"`
+	Instance string `json:"instance,omitempty"`
+	// Errors lists every invalid field of request rejected by validation
+	Errors []Violation `json:"errors,omitempty"`
+}
+
+type ProductListResponse struct {
+	Products []Product `json:"products"`
+	Total    int       `json:"total"`
+	Limit    int       `json:"limit"`
+	Offset   int       `json:"offset"`
 }
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// ErrSearchUnavailable is returned when full-text search index does not exist,
+// which happens when SQLite driver was built without FTS5 support (build tag sqlite_fts5)
+var ErrSearchUnavailable = &Error{Kind: ErrUnavailable, Code: CodeSearchUnavailable, Message: "full-text search is not available"}
+
+// SearchResult represents product matched by full-text search
+type SearchResult struct {
+	Product
+	// Highlight is HTML-escaped product name with matched words wrapped in <mark> tags
+	Highlight string `json:"highlight"`
+	// Snippet is HTML-escaped fragment of product description around matched words, marked like Highlight
+	Snippet string `json:"snippet"`
+	// Score is relevance of product to query, higher is better
+	Score float64 `json:"score"`
+}
+
+// Markers delimiting matched words in text returned by FTS5
+// They are control characters, which names and descriptions cannot contain, so text can be escaped
+// before markers are replaced by <mark> tags, see markMatches
+const (
+	matchStart = "\x02"
+	matchEnd   = "\x03"
+)
+
+// SearchOptions holds parameters of full-text product search
+type SearchOptions struct {
+	// Query is free text, every word of which must prefix-match word in product name or description
+	Query string
+	// Category optionally restricts results to products in category or any of its descendants
+	Category string
+	Limit    int
+}
+
+// SearchProducts finds products whose name or description match words of query
+// Discontinued products are not found
+// Results are ranked by bm25, matches in product name weigh more than matches in description
+// takes database connection and search options as parameters
+// returns slice of matched products and any error encountered
+func SearchProducts(ctx context.Context, db *sql.DB, options SearchOptions) ([]SearchResult, error) {
+	// Convert free text into FTS5 query
+	match := buildMatchExpression(options.Query)
+	if match == "" {
+		return nil, ValidationError(CodeInvalidSearchQuery, "search query must contain at least one word")
+	}
+
+	// Check that search index exists
+	var count int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'products_fts'").Scan(&count)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error checking search index", "error", err)
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrSearchUnavailable
+	}
+
+	// Apply default and maximum number of results
+	limit := options.Limit
+	if limit <= 0 {
+		limit = DefaultProductsLimit
+	}
+	if limit > MaxProductsLimit {
+		limit = MaxProductsLimit
+	}
+
+	query := `
+		SELECT ` + productColumns + `,
+			highlight(products_fts, 0, ?, ?),
+			snippet(products_fts, 1, ?, ?, '…', 12),
+			bm25(products_fts, 10.0, 1.0)
+		FROM products_fts
+		JOIN products p ON p.id = products_fts.rowid
+		WHERE products_fts MATCH ? AND p.discontinued_at IS NULL
+	`
+	args := []interface{}{matchStart, matchEnd, matchStart, matchEnd, match}
+
+	// Restrict results to products of category subtree if category is specified
+	if options.Category != "" {
+		query = `
+		WITH RECURSIVE subtree(id) AS (
+			SELECT id FROM categories WHERE name = ?
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)` + query + `
+		AND p.id IN (
+			SELECT pc.product_id FROM product_categories pc WHERE pc.category_id IN (SELECT id FROM subtree)
+		)
+	`
+		args = append([]interface{}{options.Category}, args...)
+	}
+	query += " ORDER BY bm25(products_fts, 10.0, 1.0) LIMIT ?"
+	args = append(args, limit)
+
+	// Execute search query
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error executing search query", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Initialize slice to store matched products
+	results := []SearchResult{}
+	// Iterate through query result rows
+	for rows.Next() {
+		var result SearchResult
+		var bm25 float64
+		product, err := scanProduct(scannerWithExtra{rows, []interface{}{&result.Highlight, &result.Snippet, &bm25}})
+		if err != nil {
+			logger.FromContext(ctx).Error("Error scanning row from query result", "error", err)
+			return nil, err
+		}
+		result.Product = product
+		result.Highlight = markMatches(result.Highlight)
+		result.Snippet = markMatches(result.Snippet)
+		// bm25 is negative and lower for better matches
+		result.Score = -bm25
+		results = append(results, result)
+	}
+	// Check for any errors encountered during iteration
+	if err := rows.Err(); err != nil {
+		logger.FromContext(ctx).Error("Error processing query result", "error", err)
+		return nil, err
+	}
+
+	logger.FromContext(ctx).Debug("Searched products", "query", options.Query, "category", options.Category, "count", len(results))
+	return results, nil
+}
+
+// buildMatchExpression converts free text into FTS5 query where every word is quoted prefix term
+// Quoting keeps FTS5 operators and special characters in user input from being interpreted
+// returns empty string if text contains no words
+func buildMatchExpression(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		terms = append(terms, `"`+word+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// markMatches escapes text for HTML and then wraps words between match markers in <mark> tags,
+// so that product data rendered as HTML cannot inject markup
+func markMatches(text string) string {
+	return strings.NewReplacer(matchStart, "<mark>", matchEnd, "</mark>").Replace(html.EscapeString(text))
+}
+
+// scannerWithExtra scans product columns followed by additional columns into extra destinations
+type scannerWithExtra struct {
+	row   rowScanner
+	extra []interface{}
+}
+
+// Scan appends extra destinations to destinations of product columns
+func (s scannerWithExtra) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
@@ -1,130 +1,189 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/internal/metrics"
 	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/crypto/bcrypt"
-	"os"
+	"sync"
 	"time"
 )
 
+// User roles ordered from least to most privileged
+const (
+	// RoleViewer can only read catalog, which is assigned to every registered user
+	RoleViewer = "viewer"
+	// RoleEditor can additionally create, update and delete products and categories
+	RoleEditor = "editor"
+	// RoleAdmin can additionally delete categories and assign roles to users
+	RoleAdmin = "admin"
+)
+
+// roleRanks maps each role to its privilege level
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
 type User struct {
 	ID       int    `json:"id"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Role     string `json:"role,omitempty"`
 }
 
-// SECRET_KEY Get secret key from environment variable
-var SECRET_KEY = os.Getenv("SECRET_KEY")
+// TokenClaims holds user data carried by JWT token
+type TokenClaims struct {
+	UserID   int
+	Username string
+	Role     string
+}
+
+// DefaultTokenTTL is lifetime of tokens issued before ConfigureTokens is called
+const DefaultTokenTTL = 24 * time.Hour
+
+// TokenSettings holds secret key signing JWT tokens and lifetime of issued tokens
+type TokenSettings struct {
+	SecretKey string
+	TTL       time.Duration
+}
 
-// IsTokenValid checks if token is valid
-func IsTokenValid(db *sql.DB, tokenString string) (bool, error) {
+// tokenSettings are settings used by GenerateJWT and ParseToken, guarded by tokenSettingsMu
+var (
+	tokenSettingsMu sync.RWMutex
+	tokenSettings   = TokenSettings{TTL: DefaultTokenTTL}
+)
 
-	// Check token validity
-	validToken, err := CheckToken(tokenString)
-	if err != nil || !validToken {
-		logger.Println("Error checking token validity:", err)
-		return false, errors.New("invalid token")
+// ConfigureTokens sets secret key and lifetime of tokens, zero TTL keeps DefaultTokenTTL
+func ConfigureTokens(settings TokenSettings) {
+	if settings.TTL <= 0 {
+		settings.TTL = DefaultTokenTTL
 	}
 
-	logger.Println("Token is valid")
-	return true, nil
+	tokenSettingsMu.Lock()
+	defer tokenSettingsMu.Unlock()
+	tokenSettings = settings
 }
 
-// RegisterUser registers new user in database
-func RegisterUser(db *sql.DB, user *User) error {
-	// Check if username already exists
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", user.Username).Scan(&count)
-	if err != nil {
-		logger.Println("Error checking if username exists:", err)
-		return err
-	}
-	if count > 0 {
-		return errors.New("username already exists")
-	}
+// currentTokenSettings returns settings used to issue and check tokens
+func currentTokenSettings() TokenSettings {
+	tokenSettingsMu.RLock()
+	defer tokenSettingsMu.RUnlock()
+	return tokenSettings
+}
 
+// RegisterUser registers new user in database
+// New users always get viewer role, role provided by user is ignored
+// Usernames are unique, registering taken username returns error of kind ErrConflict
+func RegisterUser(ctx context.Context, db Executor, user *User) error {
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
-		logger.Println("Error hashing password:", err)
+		logger.FromContext(ctx).Error("Error hashing password", "error", err)
 		return err
 	}
 
 	// Insert new user in database
-	_, err = db.Exec("INSERT INTO users (username, password) VALUES (?, ?)", user.Username, hashedPassword)
+	user.Role = RoleViewer
+	_, err = db.ExecContext(ctx, "INSERT INTO users (username, password, role) VALUES (?, ?, ?)", user.Username, hashedPassword, user.Role)
+	if isUniqueViolation(err) {
+		logger.FromContext(ctx).Info("Username is already taken", "username", user.Username)
+		return ConflictError(CodeUsernameTaken, "username %q is already taken", user.Username).Wrap(err)
+	}
 	if err != nil {
-		logger.Println("Error inserting new user in database:", err)
+		logger.FromContext(ctx).Error("Error inserting new user in database", "error", err)
 		return err
 	}
 
-	logger.Println("Successfully registered")
+	logger.FromContext(ctx).Info("User registered", "username", user.Username)
 
 	return nil
 }
 
 // LoginUser login user and generate JWT token
-func LoginUser(db *sql.DB, user *User) (string, error) {
+func LoginUser(ctx context.Context, db *sql.DB, user *User) (string, error) {
 	var dbUser User
-	err := db.QueryRow("SELECT id, username, password FROM users WHERE username = ?", user.Username).Scan(&dbUser.ID, &dbUser.Username, &dbUser.Password)
+	err := db.QueryRowContext(ctx, "SELECT id, username, password, role FROM users WHERE username = ?", user.Username).Scan(&dbUser.ID, &dbUser.Username, &dbUser.Password, &dbUser.Role)
 	if err != nil {
-		logger.Println("Error retrieving user from database:", errors.New("not registered yet"))
-		return "", err
+		if !errors.Is(err, sql.ErrNoRows) {
+			metrics.ObserveLogin(metrics.LoginError)
+			logger.FromContext(ctx).Error("Error selecting user", "username", user.Username, "error", err)
+			return "", err
+		}
+		// Unknown user and wrong password are reported alike, so that usernames cannot be probed
+		metrics.ObserveLogin(metrics.LoginUnknownUser)
+		logger.FromContext(ctx).Info("Login of unknown user", "username", user.Username)
+		return "", UnauthorizedError(CodeInvalidCredentials, "invalid username or password").Wrap(err)
 	}
 
 	// Compare stored password hash with provided password
 	err = bcrypt.CompareHashAndPassword([]byte(dbUser.Password), []byte(user.Password))
 	if err != nil {
-		return "", errors.New("invalid password")
+		metrics.ObserveLogin(metrics.LoginInvalidPassword)
+		logger.FromContext(ctx).Info("Login with invalid password", "username", user.Username)
+		return "", UnauthorizedError(CodeInvalidCredentials, "invalid username or password")
 	}
 
 	// Generate JWT token
 	token, err := GenerateJWT(&dbUser)
 	if err != nil {
-		logger.Println("Error generating JWT token:", err)
+		metrics.ObserveLogin(metrics.LoginError)
+		logger.FromContext(ctx).Error("Error generating JWT token", "error", err)
 		return "", err
 	}
 
-	logger.Println("Successfully logged in")
+	metrics.ObserveLogin(metrics.LoginSucceeded)
+	logger.FromContext(ctx).Info("User logged in", "username", dbUser.Username)
 
 	return token, nil
 }
 
-func CheckToken(tokenString string) (bool, error) {
-
+// ParseToken parses JWT token, checks its signature and expiration and extracts user claims
+// returns claims of valid token or error describing why token is invalid
+func ParseToken(tokenString string) (*TokenClaims, error) {
 	// Parse token
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(SECRET_KEY), nil
+		return []byte(currentTokenSettings().SecretKey), nil
 	})
 	if err != nil {
-		return false, err
+		return nil, tokenError(err)
 	}
 
 	// Check if token is valid
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		logger.Println("Token is invalid")
-		return false, nil
+		logger.Debug("Token is invalid")
+		return nil, UnauthorizedError(CodeInvalidToken, "invalid token")
 	}
 
 	// Check if expiration claim exists and validate it
 	expiration, ok := claims["exp"].(float64)
 	if !ok {
-		logger.Println("no expiration claim found")
-		return false, nil
+		logger.Debug("No expiration claim found")
+		return nil, UnauthorizedError(CodeInvalidToken, "no expiration claim found")
 	}
 
 	if int64(expiration) < time.Now().Unix() {
-		logger.Println("Token has expired")
-		return false, nil
+		logger.Debug("Token has expired")
+		return nil, UnauthorizedError(CodeTokenExpired, "token has expired")
 	}
 
-	return true, nil
+	// Extract user claims, tokens issued before roles were introduced carry no role
+	tokenClaims := &TokenClaims{}
+	if id, ok := claims["id"].(float64); ok {
+		tokenClaims.UserID = int(id)
+	}
+	tokenClaims.Username, _ = claims["sub"].(string)
+	tokenClaims.Role, _ = claims["role"].(string)
+
+	return tokenClaims, nil
 }
 
 // GenerateJWT generates JWT token for user with additional claims
@@ -136,14 +195,121 @@ func GenerateJWT(user *User) (string, error) {
 	claims := token.Claims.(jwt.MapClaims)
 	claims["id"] = user.ID
 	claims["sub"] = user.Username
+	claims["role"] = user.Role
 
 	// Add additional claims
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix()
+	settings := currentTokenSettings()
+	claims["exp"] = time.Now().Add(settings.TTL).Unix()
 
 	// Sign token with secret key
-	tokenString, err := token.SignedString([]byte(SECRET_KEY))
+	tokenString, err := token.SignedString([]byte(settings.SecretKey))
 	if err != nil {
 		return "", err
 	}
 	return tokenString, nil
 }
+
+// HasRole checks if role grants at least privileges of required role
+// Unknown roles grant no privileges
+func HasRole(role, required string) bool {
+	rank, ok := roleRanks[role]
+	if !ok {
+		return false
+	}
+	return rank >= roleRanks[required]
+}
+
+// GetUserRole retrieves current role of user with specified ID and username
+// Roles can change after tokens are issued, so role in token must not be trusted for authorization
+// Returns error of kind ErrUnauthorized if user does not exist anymore, so that tokens of removed users are rejected
+func GetUserRole(ctx context.Context, db Executor, userID int, username string) (string, error) {
+	var role string
+	err := db.QueryRowContext(ctx, "SELECT role FROM users WHERE id = ? AND username = ?", userID, username).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.FromContext(ctx).Info("User of token not found", "id", userID, "username", username)
+		return "", UnauthorizedError(CodeInvalidToken, "user of token does not exist").Wrap(err)
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Error selecting user role", "username", username, "error", err)
+		return "", err
+	}
+	return role, nil
+}
+
+// IsValidRole checks if role is one of known user roles
+func IsValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// SetUserRole assigns role to user with specified username
+// Returns error if role is unknown or user does not exist
+func SetUserRole(ctx context.Context, db Executor, username, role string) error {
+	// Check if role is known
+	if !IsValidRole(role) {
+		return ValidationError(CodeInvalidRole, "unknown role %q", role)
+	}
+
+	// Update role of user in database
+	result, err := db.ExecContext(ctx, "UPDATE users SET role = ? WHERE username = ?", role, username)
+	if err != nil {
+		logger.FromContext(ctx).Error("Error updating user role in database", "error", err)
+		return err
+	}
+
+	// Check if user exists
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.FromContext(ctx).Error("Error getting rows affected", "error", err)
+		return err
+	}
+	if rowsAffected == 0 {
+		logger.FromContext(ctx).Info("User not found", "username", username)
+		return NotFoundError(CodeUserNotFound, "user %q not found", username)
+	}
+
+	logger.FromContext(ctx).Info("User role set", "username", username, "role", role)
+	return nil
+}
+
+// BootstrapAdmin makes sure that user with specified username exists
+// Registers user with provided password and admin role if user does not exist yet,
+// existing user is left unchanged, so that later changes of its role are kept across restarts
+// Used at startup so that fresh installation has someone able to assign roles
+func BootstrapAdmin(ctx context.Context, db *sql.DB, username, password string) error {
+	// Register and promote user in single transaction, so that bootstrapped user is never left without admin role
+	return WithTx(ctx, db, func(tx *sql.Tx) error {
+		// Check if user already exists
+		var count int
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE username = ?", username).Scan(&count)
+		if err != nil {
+			logger.FromContext(ctx).Error("Error checking if username exists", "error", err)
+			return err
+		}
+
+		// Keep existing user and its role
+		if count > 0 {
+			logger.FromContext(ctx).Debug("Admin user already exists", "username", username)
+			return nil
+		}
+
+		// Register user and promote it to admin
+		if err := RegisterUser(ctx, tx, &User{Username: username, Password: password}); err != nil {
+			return err
+		}
+		return SetUserRole(ctx, tx, username, RoleAdmin)
+	})
+}
+
+// tokenError converts error of token parsing into error of kind ErrUnauthorized
+func tokenError(err error) error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorExpired != 0 {
+		return UnauthorizedError(CodeTokenExpired, "token has expired").Wrap(err)
+	}
+	return UnauthorizedError(CodeInvalidToken, "invalid token").Wrap(err)
+}
@@ -0,0 +1,30 @@
+package models
+
+// AnyVersion passed as expected version turns off version check, so that resource is changed whatever its version is
+// Versions of stored products and categories start at 1 and grow by one with every change
+const AnyVersion int64 = 0
+
+// versionCondition is SQL condition matching rows of expected version, or any row for AnyVersion
+// Takes expected version twice as arguments, see versionArgs
+const versionCondition = "(? = 0 OR version = ?)"
+
+// versionArgs returns arguments of versionCondition for expected version
+func versionArgs(version int64) []interface{} {
+	return []interface{}{version, version}
+}
+
+// versionMismatchError creates error reporting that resource was changed since client read expected version of it
+func versionMismatchError(resource, name string) error {
+	return PreconditionFailedError(CodeVersionMismatch, "%s %q does not have expected version, it was changed since it was read",
+		resource, name)
+}
+
+// CheckVersion checks that current version of resource with specified name equals expected version,
+// unless expected version is AnyVersion
+// Returns error of kind ErrPreconditionFailed if versions differ
+func CheckVersion(resource, name string, current, expected int64) error {
+	if expected != AnyVersion && current != expected {
+		return versionMismatchError(resource, name)
+	}
+	return nil
+}
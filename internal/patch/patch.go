@@ -0,0 +1,245 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+)
+
+// Media types of supported patch formats
+const (
+	// MergePatchType is media type of JSON Merge Patch (RFC 7396)
+	MergePatchType = "application/merge-patch+json"
+	// JSONPatchType is media type of JSON Patch (RFC 6902)
+	JSONPatchType = "application/json-patch+json"
+)
+
+// Patch describes changes of JSON document
+// Documents are values decoded from JSON with numbers kept as json.Number, see Value
+type Patch interface {
+	// Apply applies all changes to document and returns changed document
+	// Document may be modified even if Apply fails, so callers should apply patch to copy of document they keep
+	Apply(document interface{}) (interface{}, error)
+}
+
+// Decode decodes patch of specified media type from JSON data
+// Returns error of kind models.ErrMalformed if patch is not valid JSON or is not valid patch of its type
+func Decode(mediaType string, data []byte) (Patch, error) {
+	switch mediaType {
+	case MergePatchType:
+		value, err := decodeValue(data)
+		if err != nil {
+			return nil, invalidPatchError("patch is not valid JSON").Wrap(err)
+		}
+		return MergePatch{Value: value}, nil
+	case JSONPatchType:
+		var operations JSONPatch
+		if err := json.Unmarshal(data, &operations); err != nil {
+			return nil, invalidPatchError("patch must be array of operations").Wrap(err)
+		}
+		for i, operation := range operations {
+			if err := operation.check(); err != nil {
+				return nil, invalidPatchError("operation %d: %s", i, err)
+			}
+		}
+		return operations, nil
+	}
+	return nil, fmt.Errorf("unsupported patch media type %q", mediaType)
+}
+
+// Value converts v into JSON document that patches can be applied to
+// Numbers are kept as json.Number, so that large integers are not rounded
+func Value(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeValue(data)
+}
+
+// MergePatch is JSON Merge Patch (RFC 7396)
+// Members of patch object replace members of document, members with null value are removed,
+// and nested objects are merged recursively; patch that is not object replaces whole document
+type MergePatch struct {
+	Value interface{}
+}
+
+// Apply merges patch into document, merge patch can always be applied
+func (p MergePatch) Apply(document interface{}) (interface{}, error) {
+	return merge(document, p.Value), nil
+}
+
+// merge merges patch into target as described by RFC 7396
+func merge(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = merge(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// JSONPatch is JSON Patch (RFC 6902), that is sequence of operations applied in order
+type JSONPatch []Operation
+
+// Operation is single operation of JSON Patch
+type Operation struct {
+	// Op is one of add, remove, replace, move, copy and test
+	Op string `json:"op"`
+	// Path is JSON Pointer (RFC 6901) to location in document operation applies to
+	Path string `json:"path"`
+	// From is JSON Pointer to source location of move and copy operations
+	From string `json:"from,omitempty"`
+	// Value is value of add, replace and test operations, nil if member is missing
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies operations to document in order
+// Returns error of kind models.ErrConflict if operation conflicts with current document,
+// that is if location it refers to does not exist or if test operation fails,
+// and error of kind models.ErrValidation with code models.CodeInvalidPatch if operation is invalid for document,
+// for example if array index is not number or is out of range
+func (p JSONPatch) Apply(document interface{}) (interface{}, error) {
+	for i, operation := range p {
+		var err error
+		if document, err = operation.apply(document); err != nil {
+			var invalid *invalidOperationError
+			if errors.As(err, &invalid) {
+				return nil, models.ValidationError(models.CodeInvalidPatch, "operation %d (%s %s): %s",
+					i, operation.Op, operation.Path, err)
+			}
+			return nil, models.ConflictError(models.CodePatchConflict, "operation %d (%s %s): %s",
+				i, operation.Op, operation.Path, err)
+		}
+	}
+	return document, nil
+}
+
+// check checks that operation has all members its type requires
+func (o *Operation) check() error {
+	switch o.Op {
+	case "add", "replace", "test":
+		if o.Value == nil {
+			return fmt.Errorf("%s operation requires value", o.Op)
+		}
+		if _, err := decodeValue(o.Value); err != nil {
+			return fmt.Errorf("value is not valid JSON")
+		}
+	case "move", "copy":
+		if _, err := parsePointer(o.From); err != nil {
+			return fmt.Errorf("from: %w", err)
+		}
+	case "remove":
+	default:
+		return fmt.Errorf("unknown operation %q", o.Op)
+	}
+	if _, err := parsePointer(o.Path); err != nil {
+		return fmt.Errorf("path: %w", err)
+	}
+	return nil
+}
+
+// apply applies operation to document and returns changed document
+// Operation of patch not built by Decode is checked first, so that malformed operation is never reported as conflict
+func (o *Operation) apply(document interface{}) (interface{}, error) {
+	if err := o.check(); err != nil {
+		return nil, invalidOperation("%s", err)
+	}
+	path, err := parsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case "add":
+		value, err := decodeValue(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(document, path, value)
+	case "remove":
+		document, _, err = remove(document, path)
+		return document, err
+	case "replace":
+		value, err := decodeValue(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		// Whole document is replaced without removing it first
+		if len(path) == 0 {
+			return value, nil
+		}
+		if document, _, err = remove(document, path); err != nil {
+			return nil, err
+		}
+		return add(document, path, value)
+	case "move":
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, invalidOperation("location cannot be moved into one of its children")
+		}
+		document, value, err := remove(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(document, path, value)
+	case "copy":
+		from, err := parsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(document, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(document, path, copyValue(value))
+	case "test":
+		expected, err := decodeValue(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(document, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(value, expected) {
+			return nil, fmt.Errorf("value differs from expected one")
+		}
+		return document, nil
+	}
+	return nil, fmt.Errorf("unknown operation %q", o.Op)
+}
+
+// decodeValue decodes JSON value keeping numbers as json.Number
+func decodeValue(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("data after JSON value")
+	}
+	return value, nil
+}
+
+// invalidPatchError creates error of kind models.ErrMalformed reporting that patch itself is invalid
+func invalidPatchError(format string, args ...interface{}) *models.Error {
+	return &models.Error{Kind: models.ErrMalformed, Code: models.CodeInvalidPatch, Message: fmt.Sprintf(format, args...)}
+}
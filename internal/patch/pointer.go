@@ -0,0 +1,260 @@
+package patch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// invalidOperationError reports operation that is invalid for document it is applied to,
+// such as array index that is not number, has leading zeros or is out of range
+// Unlike missing locations and failed tests, it does not depend on changes made by other clients
+type invalidOperationError struct {
+	message string
+}
+
+// Error returns message of error
+func (e *invalidOperationError) Error() string {
+	return e.message
+}
+
+// invalidOperation creates invalidOperationError with message formatted according to format specifier
+func invalidOperation(format string, args ...interface{}) error {
+	return &invalidOperationError{message: fmt.Sprintf(format, args...)}
+}
+
+// parsePointer splits JSON Pointer (RFC 6901) into unescaped reference tokens
+// Empty pointer refers to whole document and has no tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("pointer %q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		// ~ may only escape / as ~1 and ~ itself as ~0
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || token[j+1] != '0' && token[j+1] != '1') {
+				return nil, fmt.Errorf("pointer %q contains invalid escape sequence", pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// get returns value at location of document referred to by path
+func get(document interface{}, path []string) (interface{}, error) {
+	value := document
+	for i, token := range path {
+		switch node := value.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, notFoundError(path[:i+1])
+			}
+			value = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			value = node[index]
+		default:
+			return nil, notFoundError(path[:i+1])
+		}
+	}
+	return value, nil
+}
+
+// add adds value to document at location referred to by path and returns changed document
+// Existing member of object is replaced, element is inserted into array before element at index,
+// or appended to array if index is "-"
+func add(document interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return change(document, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			index := len(node)
+			if token != "-" {
+				var err error
+				if index, err = arrayIndex(token, len(node)+1); err != nil {
+					return nil, err
+				}
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		return nil, notFoundError(path[:len(path)-1])
+	})
+}
+
+// remove removes value at location referred to by path from document
+// Returns changed document and removed value
+func remove(document interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, invalidOperation("whole document cannot be removed")
+	}
+	var removed interface{}
+	document, err := change(document, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, notFoundError(path)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			index, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			removed = node[index]
+			return append(node[:index], node[index+1:]...), nil
+		}
+		return nil, notFoundError(path[:len(path)-1])
+	})
+	return document, removed, err
+}
+
+// change calls fn with parent of location referred to by non-empty path and with last token of path
+// Value returned by fn replaces parent in document, as arrays change when elements are added or removed
+func change(document interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(document, path[0])
+	}
+
+	token := path[0]
+	switch node := document.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, notFoundError(path[:1])
+		}
+		child, err := change(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = child
+		return node, nil
+	case []interface{}:
+		index, err := arrayIndex(token, len(node))
+		if err != nil {
+			return nil, err
+		}
+		child, err := change(node[index], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = child
+		return node, nil
+	}
+	return nil, notFoundError(path[:1])
+}
+
+// arrayIndex parses reference token as index of array element, which must be less than limit
+func arrayIndex(token string, limit int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || token != strconv.Itoa(index) {
+		return 0, invalidOperation("%q is not valid array index", token)
+	}
+	if index >= limit {
+		return 0, invalidOperation("array index %d is out of range", index)
+	}
+	return index, nil
+}
+
+// isPrefix checks if path starts with all tokens of prefix
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// copyValue returns deep copy of JSON value, so that changes of copy do not affect original
+func copyValue(value interface{}) interface{} {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(node))
+		for name, child := range node {
+			copied[name] = copyValue(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+		for i, child := range node {
+			copied[i] = copyValue(child)
+		}
+		return copied
+	}
+	return value
+}
+
+// equal checks if JSON values are equal, numbers are compared by value, so that 1 equals 1.0
+func equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for name, value := range x {
+			other, ok := y[name]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if x == y {
+			return true
+		}
+		xf, xErr := x.Float64()
+		yf, yErr := y.Float64()
+		return xErr == nil && yErr == nil && xf == yf
+	}
+	return a == b
+}
+
+// notFoundError creates error reporting that location referred to by path does not exist
+func notFoundError(path []string) error {
+	escaped := make([]string, len(path))
+	for i, token := range path {
+		escaped[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+	}
+	return fmt.Errorf("location /%s does not exist", strings.Join(escaped, "/"))
+}
@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when job runs next
+type Schedule interface {
+	// Next returns first time after specified one when job should run
+	Next(after time.Time) time.Time
+}
+
+// Interval runs job every fixed duration, counted from end of previous run
+type Interval time.Duration
+
+// Every returns schedule running job every specified duration
+func Every(interval time.Duration) Interval {
+	return Interval(interval)
+}
+
+// Next returns time specified duration after after
+func (i Interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+// String returns interval in time.Duration format
+func (i Interval) String() string {
+	return time.Duration(i).String()
+}
+
+// descriptors maps predefined schedules to cron expressions
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses schedule specification, which is one of
+// duration such as "30m", "@every 30m", predefined schedule such as "@daily" or five-field cron expression
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, errors.New("empty schedule")
+	}
+
+	// Fixed intervals
+	if strings.HasPrefix(spec, "@every ") {
+		spec = strings.TrimSpace(strings.TrimPrefix(spec, "@every "))
+	}
+	if interval, err := time.ParseDuration(spec); err == nil {
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval %q must be positive", spec)
+		}
+		return Every(interval), nil
+	}
+
+	// Predefined schedules
+	if expression, ok := descriptors[spec]; ok {
+		spec = expression
+	}
+	return ParseCron(spec)
+}
+
+// Cron runs job at times matching cron expression, evaluated in time zone of time passed to Next
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set when day of month or day of week field allows every day, such as "*" or "1-31"
+	domAny, dowAny bool
+}
+
+// cronField describes allowed values of single cron field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses five-field cron expression: minute, hour, day of month, month and day of week
+// Every field accepts "*", single values, ranges "a-b", lists "a,b" and steps "*/n" or "a-b/n"
+// Day of week 0 and 7 both mean Sunday; when both day fields are restricted, matching either of them is enough
+func ParseCron(expression string) (*Cron, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", expression, len(cronFields))
+	}
+
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expression, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday may be written as 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+
+	return &Cron{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: sets[2] == fullCronSet(1, 31),
+		dowAny: sets[4] == fullCronSet(0, 6),
+	}, nil
+}
+
+// fullCronSet returns set of all values from min to max stored as bits
+func fullCronSet(min, max int) uint64 {
+	var set uint64
+	for value := min; value <= max; value++ {
+		set |= 1 << value
+	}
+	return set
+}
+
+// parseCronField parses single cron field into set of allowed values stored as bits
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		// Split off step
+		rangePart, step := part, 1
+		if slash := strings.Index(part, "/"); slash >= 0 {
+			var err error
+			rangePart = part[:slash]
+			step, err = strconv.Atoi(part[slash+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", bounds.name, part)
+			}
+		}
+
+		// Resolve range of values
+		low, high := bounds.min, bounds.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			ends := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(ends[0])
+			high, err2 = strconv.Atoi(ends[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range in %s field %q", bounds.name, part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field %q", bounds.name, part)
+			}
+			low, high = value, value
+			// Single value with step, such as "5/15", runs from value to end of range
+			if step > 1 {
+				high = bounds.max
+			}
+		}
+		if low < bounds.min || high > bounds.max || low > high {
+			return 0, fmt.Errorf("%s field %q out of range %d-%d", bounds.name, part, bounds.min, bounds.max)
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
+
+// Next returns first minute after after matching expression, or zero time if there is none within five years
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether day of t matches day of month and day of week fields
+func (c *Cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// Job is background task run by scheduler
+type Job struct {
+	// Name identifies job in logs
+	Name string
+	// Schedule decides when job runs
+	Schedule Schedule
+	// RunOnStart runs job immediately when scheduler starts, before first scheduled time
+	RunOnStart bool
+	// Run performs job, context is cancelled when scheduler stops
+	Run func(ctx context.Context) error
+}
+
+// Scheduler runs registered jobs on their schedules, each job in its own goroutine
+// Runs of same job never overlap: next run is planned only after previous one finishes
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []Job
+	started bool
+	wg      sync.WaitGroup
+}
+
+// New creates scheduler without jobs
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add registers job, jobs can only be registered before scheduler starts
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" {
+		return errors.New("job requires name")
+	}
+	if job.Schedule == nil || job.Run == nil {
+		return fmt.Errorf("job %s requires schedule and run function", job.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return fmt.Errorf("cannot add job %s to started scheduler", job.Name)
+	}
+	s.jobs = append(s.jobs, job)
+	return nil
+}
+
+// Start starts running registered jobs until context is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go func(job Job) {
+			defer s.wg.Done()
+			s.loop(logger.With(ctx, "job", job.Name), job)
+		}(job)
+	}
+}
+
+// Wait blocks until all jobs have stopped after context passed to Start was cancelled
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// loop runs job at times given by its schedule until context is cancelled
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	if job.RunOnStart {
+		s.run(ctx, job)
+	}
+
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.FromContext(ctx).Info("Job has no more scheduled runs")
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			s.run(ctx, job)
+		}
+	}
+}
+
+// run runs job once, logging its duration and error
+// Panic in job is logged with stack trace and does not stop scheduler
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	start := time.Now()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logger.FromContext(ctx).Error("Job panicked", "panic", recovered, "stack", string(debug.Stack()))
+		}
+	}()
+
+	if err := job.Run(ctx); err != nil {
+		logger.FromContext(ctx).Error("Job failed", "duration", time.Since(start), "error", err)
+		return
+	}
+	logger.FromContext(ctx).Info("Job finished", "duration", time.Since(start))
+}
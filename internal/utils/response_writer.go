@@ -2,40 +2,86 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
 	"github.com/MaximInnopolis/ProductCatalog/internal/models"
 	"net/http"
 )
 
-// WriteErrorJSONResponse writes error JSON response with given error message and status code to provided http.ResponseWriter
-func WriteErrorJSONResponse(w http.ResponseWriter, err error, status int) {
-	response := models.ErrorResponse{Error: err.Error()}
-	w.WriteHeader(status)
-	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(response)
-	if err != nil {
-		WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
+// ProblemContentType is content type of error responses
+const ProblemContentType = "application/problem+json"
+
+// errorStatuses maps kinds of domain errors to status codes of responses
+var errorStatuses = []struct {
+	kind   error
+	status int
+}{
+	{models.ErrNotFound, http.StatusNotFound},
+	{models.ErrConflict, http.StatusConflict},
+	{models.ErrValidation, http.StatusUnprocessableEntity},
+	{models.ErrUnauthorized, http.StatusUnauthorized},
+	{models.ErrForbidden, http.StatusForbidden},
+	{models.ErrUnavailable, http.StatusServiceUnavailable},
+	{models.ErrMalformed, http.StatusBadRequest},
+	{models.ErrTooLarge, http.StatusRequestEntityTooLarge},
+	{models.ErrPreconditionFailed, http.StatusPreconditionFailed},
+	{models.ErrPreconditionRequired, http.StatusPreconditionRequired},
+	{models.ErrUnsupportedMediaType, http.StatusUnsupportedMediaType},
+	{models.ErrMethodNotAllowed, http.StatusMethodNotAllowed},
+	{models.ErrInternal, http.StatusInternalServerError},
+}
+
+// WriteError writes problem response describing error of request
+// Status code and error code follow from kind of domain error, see models.Error
+// Other errors are unexpected, they are logged and reported as internal server error without details
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	var domainErr *models.Error
+	if errors.As(err, &domainErr) {
+		for _, mapping := range errorStatuses {
+			if errors.Is(domainErr, mapping.kind) {
+				writeProblem(w, r, mapping.status, domainErr.Code, domainErr.Message, domainErr.Violations)
+				return
+			}
+		}
 	}
+
+	logger.FromContext(r.Context()).Error("Request failed", "error", err)
+	writeProblem(w, r, http.StatusInternalServerError, models.CodeInternalError, "internal server error", nil)
+}
+
+// writeProblem writes problem response, request is used to fill instance of problem if provided
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string, violations []models.Violation) {
+	response := models.Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: violations,
+	}
+	if r != nil {
+		response.Instance = r.URL.Path
+	}
+
+	// Headers must be set before status code is written, later changes are ignored
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
 }
 
 // WriteJSONResponse writes JSON response with given message and status code to provided http.ResponseWriter
 func WriteJSONResponse(w http.ResponseWriter, status int, message string) {
 	response := models.ResponseMessage{Message: message}
-	w.WriteHeader(status)
 	w.Header().Set("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(response)
-	if err != nil {
-		WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
-	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
 }
 
 // WriteTokenJSONResponse writes JSON response with given message, status code, and JWT token to provided http.ResponseWriter
-
 func WriteTokenJSONResponse(w http.ResponseWriter, status int, message, token string) {
 	response := models.TokenResponseMessage{Message: message, Token: token}
-	w.WriteHeader(status)
 	w.Header().Set("Content-Type", "application/json")
-	err := json.NewEncoder(w).Encode(response)
-	if err != nil {
-		WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
-	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
 }
@@ -0,0 +1,155 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// unmarshalerType is type of json.Unmarshaler, values of types implementing it decode themselves
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// DecodeJSON strictly decodes JSON body of request into struct pointed to by v and validates it with Validate
+// Unknown fields, trailing data after JSON value and empty body are rejected with error of kind models.ErrMalformed
+// Body exceeding limit set by http.MaxBytesReader is rejected with error of kind models.ErrTooLarge
+func DecodeJSON(r *http.Request, v interface{}) error {
+	if err := Decode(r, v); err != nil {
+		return err
+	}
+	return Validate(v)
+}
+
+// Decode strictly decodes JSON body of request into value pointed to by v like DecodeJSON, but does not validate it
+// Used for bodies that are not structs, such as patches
+func Decode(r *http.Request, v interface{}) error {
+	return decode(r.Body, v)
+}
+
+// Unmarshal strictly decodes JSON document into struct pointed to by v and validates it with Validate
+// Used for documents built by server from request, such as resources changed by patch
+// Unlike request bodies, keys of document must equal JSON field names exactly, as encoding/json matches them
+// case-insensitively, so document with both "name" and "Name" members would silently lose one of them
+func Unmarshal(data []byte, v interface{}) error {
+	if err := checkFieldNames(data, reflect.TypeOf(v)); err != nil {
+		return err
+	}
+	if err := decode(bytes.NewReader(data), v); err != nil {
+		return err
+	}
+	return Validate(v)
+}
+
+// decode strictly decodes single JSON value read from reader into value pointed to by v
+func decode(reader io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+
+	// Body must hold single JSON value
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if err != nil {
+			if tooLarge := decodeError(err); errors.Is(tooLarge, models.ErrTooLarge) {
+				return tooLarge
+			}
+		}
+		return malformedError("request body must contain single JSON value").Wrap(err)
+	}
+
+	return nil
+}
+
+// checkFieldNames checks that keys of JSON objects in data are exact JSON names of fields of structs of type t,
+// descending into nested structs, slices and maps
+// Values that do not match type are left for decoding to report
+func checkFieldNames(data []byte, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		var members map[string]json.RawMessage
+		if json.Unmarshal(data, &members) != nil {
+			return nil
+		}
+		fields := make(map[string]reflect.Type, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.IsExported() && field.Tag.Get("json") != "-" {
+				fields[fieldName(field)] = field.Type
+			}
+		}
+		for name, member := range members {
+			fieldType, ok := fields[name]
+			if !ok {
+				return malformedError("unknown field %q", name)
+			}
+			if err := checkFieldNames(member, fieldType); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		var elements []json.RawMessage
+		if json.Unmarshal(data, &elements) != nil {
+			return nil
+		}
+		for _, element := range elements {
+			if err := checkFieldNames(element, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		var members map[string]json.RawMessage
+		if json.Unmarshal(data, &members) != nil {
+			return nil
+		}
+		for _, member := range members {
+			if err := checkFieldNames(member, t.Elem()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeError converts error of JSON decoding into error describing what is wrong with request body
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return (&models.Error{
+			Kind:    models.ErrTooLarge,
+			Code:    models.CodeBodyTooLarge,
+			Message: fmt.Sprintf("request body must not exceed %d bytes", maxBytesErr.Limit),
+		}).Wrap(err)
+	case errors.Is(err, io.EOF):
+		return malformedError("request body is empty").Wrap(err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return malformedError("request body contains incomplete JSON").Wrap(err)
+	case errors.As(err, &syntaxErr):
+		return malformedError("request body contains invalid JSON at offset %d", syntaxErr.Offset).Wrap(err)
+	case errors.As(err, &typeErr):
+		return malformedError("field %s must be %s", typeErr.Field, typeErr.Type).Wrap(err)
+	}
+	// Unknown fields are reported by encoding/json only as text, for example `json: unknown field "id"`
+	return malformedError("%s", strings.TrimPrefix(err.Error(), "json: ")).Wrap(err)
+}
+
+// malformedError creates error of kind models.ErrMalformed with message formatted according to format specifier
+func malformedError(format string, args ...interface{}) *models.Error {
+	return &models.Error{Kind: models.ErrMalformed, Code: models.CodeMalformedBody, Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,214 @@
+package validation
+
+import (
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"golang.org/x/text/unicode/norm"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Validate normalizes and checks fields of struct pointed to by v according to rules in their validate tags
+// Rules are separated by commas and applied in order:
+//
+//	trim          removes leading and trailing white space from string
+//	nfc           converts string to Unicode normalization form C
+//	required      rejects empty string, empty slice or nil pointer
+//	min=N, max=N  limit number of characters of string, number of elements of slice or value of number
+//	charset=NAME  limits characters of string to character set, see charsets
+//	oneof=A B C   limits string to listed values
+//
+// Rules other than trim, nfc and required are skipped for empty values, so optional fields may be omitted
+// Elements of slices of structs and nested structs are validated as well
+// Returns error of kind models.ErrValidation listing every violation, or nil if struct is valid
+func Validate(v interface{}) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		panic("validation: Validate requires pointer to struct")
+	}
+
+	var violations []models.Violation
+	validateStruct(value.Elem(), "", &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	err := models.ValidationError(models.CodeValidationFailed, "request has %d invalid fields", len(violations))
+	if len(violations) == 1 {
+		err.Message = "request has 1 invalid field"
+	}
+	err.Violations = violations
+	return err
+}
+
+// zeroWidthJoiner joins emoji into single glyph
+const zeroWidthJoiner = '\u200d'
+
+// charsets maps names of character sets accepted by charset rule to functions checking single character
+var charsets = map[string]func(r rune) bool{
+	// name allows any printable character of single line, such as names of products and categories
+	// Zero width joiner is allowed, as it is part of many emoji
+	"name": func(r rune) bool {
+		return r == ' ' || r == zeroWidthJoiner || unicode.IsGraphic(r) && !unicode.IsSpace(r)
+	},
+	// text allows any printable character and line breaks, such as descriptions
+	"text": func(r rune) bool {
+		return r == '\n' || r == '\r' || r == '\t' || r == zeroWidthJoiner || unicode.IsGraphic(r)
+	},
+	// identifier allows ASCII letters, digits, dot, underscore and hyphen, such as usernames and SKUs
+	"identifier": func(r rune) bool {
+		return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-')
+	},
+	// upper allows ASCII capital letters, such as currency codes
+	"upper": func(r rune) bool {
+		return r >= 'A' && r <= 'Z'
+	},
+}
+
+// validateStruct validates fields of struct value, field paths are prefixed with prefix
+func validateStruct(value reflect.Value, prefix string, violations *[]models.Violation) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		path := prefix + fieldName(field)
+		validateField(value.Field(i), path, field.Tag.Get("validate"), violations)
+	}
+}
+
+// validateField applies rules to field value and validates elements of nested structs and slices of structs
+func validateField(value reflect.Value, path, tag string, violations *[]models.Violation) {
+	violate := func(code, format string, args ...interface{}) {
+		*violations = append(*violations, models.Violation{Field: path, Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+		case "trim":
+			value.SetString(strings.TrimSpace(value.String()))
+		case "nfc":
+			value.SetString(norm.NFC.String(value.String()))
+		case "required":
+			if isEmpty(value) {
+				violate("required", "is required")
+				return
+			}
+		case "min", "max":
+			if isEmpty(value) {
+				continue
+			}
+			limit, err := strconv.Atoi(arg)
+			if err != nil {
+				panic("validation: invalid limit in rule " + rule)
+			}
+			checkLimit(value, name, limit, violate)
+		case "charset":
+			if isEmpty(value) {
+				continue
+			}
+			allowed, ok := charsets[arg]
+			if !ok {
+				panic("validation: unknown charset in rule " + rule)
+			}
+			for _, r := range value.String() {
+				if !allowed(r) {
+					violate("invalid_characters", "contains character %q not allowed here", r)
+					break
+				}
+			}
+		case "oneof":
+			if isEmpty(value) {
+				continue
+			}
+			options := strings.Fields(arg)
+			if !contains(options, value.String()) {
+				violate("not_allowed", "must be one of %s", strings.Join(options, ", "))
+			}
+		default:
+			panic("validation: unknown rule " + rule)
+		}
+	}
+
+	// Validate nested structs, including elements of slices
+	switch value.Kind() {
+	case reflect.Struct:
+		validateStruct(value, path+".", violations)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < value.Len(); i++ {
+				validateStruct(value.Index(i), fmt.Sprintf("%s[%d].", path, i), violations)
+			}
+		}
+	}
+}
+
+// checkLimit checks that length of string or slice, or value of number, is within limit of min or max rule
+func checkLimit(value reflect.Value, rule string, limit int, violate func(code, format string, args ...interface{})) {
+	var size int64
+	unit := ""
+	switch value.Kind() {
+	case reflect.String:
+		size, unit = int64(utf8.RuneCountInString(value.String())), " characters"
+	case reflect.Slice:
+		size, unit = int64(value.Len()), " elements"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = value.Int()
+	default:
+		panic("validation: rule " + rule + " does not support " + value.Kind().String())
+	}
+
+	if rule == "min" && size < int64(limit) {
+		if unit == "" {
+			violate("too_small", "must be at least %d", limit)
+		} else {
+			violate("too_short", "must have at least %d%s", limit, unit)
+		}
+	}
+	if rule == "max" && size > int64(limit) {
+		if unit == "" {
+			violate("too_large", "must be at most %d", limit)
+		} else {
+			violate("too_long", "must have at most %d%s", limit, unit)
+		}
+	}
+}
+
+// isEmpty checks if value is empty string, empty slice or nil pointer
+// Numbers are never empty, so that min and max rules apply to zero
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return value.Len() == 0
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// fieldName returns name of field in JSON, which is used in paths of violations
+func fieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// contains checks if slice contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
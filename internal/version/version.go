@@ -0,0 +1,58 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version, Commit and BuildTime describe build of service
+// They are set at build time, for example:
+//
+//	go build -ldflags "-X github.com/MaximInnopolis/ProductCatalog/internal/version.Version=1.2.0" ./cmd/catalog_api_server
+//
+// If not set, Commit and BuildTime are taken from version control information embedded by go build,
+// BuildTime being time of commit then
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes build of running service
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns build information of running service
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
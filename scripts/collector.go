@@ -0,0 +1,177 @@
+package scripts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/internal/metrics"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/scheduler"
+	"sync"
+)
+
+// ErrCollectionInProgress is returned when collection is requested while another one is still running
+var ErrCollectionInProgress = errors.New("collection is already in progress")
+
+// ErrCollectorStopped is returned when collection is requested after collector was stopped
+var ErrCollectorStopped = errors.New("collector is stopped")
+
+// Collector collects products from configured sources and records every run in 'collector_runs' table
+// Source is never collected twice at same time, scheduled collection of busy source is skipped
+// Scheduled collections are run by scheduler through jobs returned by Jobs
+type Collector struct {
+	db      *sql.DB
+	sources []ScheduledSource
+
+	// ctx is cancelled by Stop to interrupt manually triggered collections
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+}
+
+// NewCollector creates collector of specified sources saving products to database
+// Runs left unfinished by previous process are marked as failed
+func NewCollector(db *sql.DB, sources []ScheduledSource) *Collector {
+	if aborted, err := models.AbortUnfinishedCollectorRuns(context.Background(), db); err != nil {
+		logger.Error("Error aborting unfinished collector runs", "error", err)
+	} else if aborted > 0 {
+		logger.Warn("Marked unfinished collector runs as failed", "count", aborted)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Collector{db: db, sources: sources, ctx: ctx, cancel: cancel, running: make(map[string]bool)}
+}
+
+// Jobs returns scheduler job for every source, collecting source on its schedule
+// Scheduled collection of source is skipped if source is still being collected, for example after manual trigger
+func (c *Collector) Jobs() []scheduler.Job {
+	jobs := make([]scheduler.Job, 0, len(c.sources))
+	for _, scheduled := range c.sources {
+		source := scheduled.Source
+		jobs = append(jobs, scheduler.Job{
+			Name:       "collect " + source.Name(),
+			Schedule:   scheduled.Schedule,
+			RunOnStart: scheduled.RunOnStart,
+			Run: func(ctx context.Context) error {
+				if !c.acquire(source) {
+					logger.FromContext(ctx).Info("Skipping collection: previous collection is still running", "source", source.Name())
+					return nil
+				}
+				c.wg.Add(1)
+				defer c.wg.Done()
+				defer c.release(source)
+				return c.collect(ctx, source)
+			},
+		})
+	}
+	return jobs
+}
+
+// Trigger starts immediate collection from all sources in background
+// returns ErrCollectionInProgress without starting anything if any source is being collected
+func (c *Collector) Trigger() error {
+	if c.ctx.Err() != nil {
+		return ErrCollectorStopped
+	}
+
+	sources := make([]Source, 0, len(c.sources))
+	for _, scheduled := range c.sources {
+		sources = append(sources, scheduled.Source)
+	}
+	if !c.acquire(sources...) {
+		return ErrCollectionInProgress
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		for _, source := range sources {
+			c.collect(c.ctx, source)
+			c.release(source)
+		}
+	}()
+	return nil
+}
+
+// Running reports whether any source is being collected right now
+func (c *Collector) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.running) > 0
+}
+
+// Stop interrupts manually triggered collections and waits until all running collections finish
+// Scheduled collections are interrupted by cancelling context of scheduler
+func (c *Collector) Stop() {
+	c.cancel()
+	c.Wait()
+}
+
+// Wait blocks until all collections started so far are finished
+func (c *Collector) Wait() {
+	c.wg.Wait()
+}
+
+// collect collects products from source and records run with its summary
+// returns error of run, if any
+func (c *Collector) collect(ctx context.Context, source Source) error {
+	// Run is recorded even if collection is cancelled
+	recordCtx := context.WithoutCancel(ctx)
+	run, err := models.StartCollectorRun(recordCtx, c.db, source.Name())
+	if err != nil {
+		return err
+	}
+
+	summary, runErr := CollectAndSaveProductsContext(ctx, c.db, source)
+	run.Inserted = summary.Inserted
+	run.Updated = summary.Updated
+	run.Unchanged = summary.Unchanged
+	run.Removed = summary.Removed
+	run.Failed = summary.Failed
+	if err := models.FinishCollectorRun(recordCtx, c.db, run, runErr); err != nil {
+		return err
+	}
+
+	metrics.ObserveCollectorRun(metrics.CollectorRun{
+		Source:   run.Source,
+		Status:   run.Status,
+		Duration: run.FinishedAt.Sub(run.StartedAt),
+		Products: map[string]int{
+			"inserted":  run.Inserted,
+			"updated":   run.Updated,
+			"unchanged": run.Unchanged,
+			"removed":   run.Removed,
+			"failed":    run.Failed,
+		},
+	})
+
+	return runErr
+}
+
+// acquire marks all specified sources as running
+// returns false without marking anything if any of sources is already running
+func (c *Collector) acquire(sources ...Source) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, source := range sources {
+		if c.running[source.Name()] {
+			return false
+		}
+	}
+	for _, source := range sources {
+		c.running[source.Name()] = true
+	}
+	return true
+}
+
+// release marks source as no longer running
+func (c *Collector) release(source Source) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.running, source.Name())
+}
@@ -10,7 +10,7 @@ func CreateRecords(db *sql.DB) error {
 	// Begin transaction
 	tx, err := db.Begin()
 	if err != nil {
-		logger.Println("Error beginning transaction:", err)
+		logger.Error("Error beginning transaction", "error", err)
 		return err
 	}
 	defer func() {
@@ -24,12 +24,12 @@ func CreateRecords(db *sql.DB) error {
 	// Insert product
 	result, err := tx.Exec("INSERT INTO products (name) VALUES (?) ON CONFLICT(name) DO NOTHING", "Bread")
 	if err != nil {
-		logger.Println("Error inserting product:", err)
+		logger.Error("Error inserting product", "error", err)
 		return err
 	}
 	productID, err := result.LastInsertId()
 	if err != nil {
-		logger.Println("Error getting last inserted product ID:", err)
+		logger.Error("Error getting last inserted product ID", "error", err)
 		return err
 	}
 
@@ -38,12 +38,12 @@ func CreateRecords(db *sql.DB) error {
 	for _, categoryName := range categories {
 		result, err = tx.Exec("INSERT INTO categories (name) VALUES (?) ON CONFLICT(name) DO NOTHING", categoryName)
 		if err != nil {
-			logger.Println("Error inserting category:", err)
+			logger.Error("Error inserting category", "error", err)
 			return err
 		}
 		categoryID, err := result.LastInsertId()
 		if err != nil {
-			logger.Println("Error getting last inserted category ID:", err)
+			logger.Error("Error getting last inserted category ID", "error", err)
 			return err
 		}
 
@@ -54,7 +54,7 @@ func CreateRecords(db *sql.DB) error {
 
 		_, err = tx.Exec("INSERT INTO product_categories (product_id, category_id) VALUES (?, ?)", productID, categoryID)
 		if err != nil {
-			logger.Println("Error inserting into product_categories:", err)
+			logger.Error("Error inserting into product_categories", "error", err)
 			return err
 		}
 	}
@@ -1,78 +1,123 @@
 package scripts
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
-	"errors"
-	"fmt"
-	"github.com/MaximInnopolis/ProductCatalog/internal/database"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
 	"github.com/MaximInnopolis/ProductCatalog/internal/models"
-	"net/http"
-	"time"
 )
 
-// StartDataCollection starts periodic data collection process to collect data from specified source and save to database
-// sets up ticker to trigger data collection at regular intervals and runs collection process in goroutine
-func StartDataCollection() {
-
-	// Set up ticker for collecting data at intervals
-	ticker := time.NewTicker(time.Hour)
+// SyncSummary holds counts of products processed during single collection from source
+type SyncSummary struct {
+	Source   string `json:"source"`
+	Inserted int    `json:"inserted"`
+	Updated  int    `json:"updated"`
+	// Adopted counts products without source taken over by source, they are counted as updated as well
+	Adopted   int `json:"adopted"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+	Failed    int `json:"failed"`
+}
 
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				// Collect and save data to database
-				CollectAndSaveProducts(database.GetDB())
-			}
-		}
-	}()
+// CollectAndSaveProducts collects data from specified source, processes it, and synchronizes it with database
+// fetches raw records from source, maps each record into product with categories and inserts or updates product
+// Products of source missing from fetched records are marked as discontinued, unless source returned no records at all
+// or some record could not be mapped and its external ID is unknown
+// If any errors occur while mapping or saving single product, logs error, counts product as failed and continues
+// returns summary of collection and error if source could not be fetched
+func CollectAndSaveProducts(db *sql.DB, source Source) (SyncSummary, error) {
+	return CollectAndSaveProductsContext(context.Background(), db, source)
 }
 
-// CollectAndSaveProducts collects data from specified source, processes it, and saves to database
-// sends HTTP request to source API to retrieve raw product data, processes data, and saves to database
-// If any errors occur during process, logs error and continues processing other products
-func CollectAndSaveProducts(db *sql.DB) error {
-	logger.Println("Started collecting....")
-	// Send HTTP request to retrieve raw product data from source
-	resp, err := http.Get("https://emojihub.yurace.pro/api/all")
+// CollectAndSaveProductsContext is like CollectAndSaveProducts, but stops when context is cancelled
+// Products saved before cancellation are kept, missing products are not discontinued after cancellation
+func CollectAndSaveProductsContext(ctx context.Context, db *sql.DB, source Source) (SyncSummary, error) {
+	summary := SyncSummary{Source: source.Name()}
+	ctx = logger.With(ctx, "source", source.Name())
+	log := logger.FromContext(ctx)
+
+	log.Info("Started collecting")
+	// Retrieve raw records from source
+	records, err := source.Fetch(ctx)
 	if err != nil {
-		logger.Println(err)
-		return err
+		log.Error("Error fetching data", "error", err)
+		return summary, err
 	}
-	defer resp.Body.Close()
 
-	// Decode raw product data from response body
-	var rawProducts []map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&rawProducts)
-	if err != nil {
-		logger.Println(err)
-		return err
+	// Products without source are taken over only by sources configured to adopt them
+	var options models.SyncOptions
+	if adopter, ok := source.(Adopter); ok {
+		options.AdoptExisting = adopter.AdoptsExisting()
 	}
 
-	// Save products to database
-	for _, rawProduct := range rawProducts {
-		productName, ok := rawProduct["name"].(string)
-		if !ok {
-			return errors.New("product name not found in source")
+	// Synchronize every product with database in its own transaction
+	seen := make([]string, 0, len(records))
+	unidentified := 0
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			log.Warn("Collection cancelled")
+			return summary, err
 		}
 
-		categoryName, ok := rawProduct["category"].(string)
-		if !ok {
-			return errors.New("category name not found in source")
+		collected, err := source.Map(record)
+		if err != nil {
+			log.Error("Error mapping record", "record", record, "error", err)
+			summary.Failed++
+			// Product of invalid record is still in source and must not be discontinued
+			if collected.Product.ExternalID != "" {
+				seen = append(seen, collected.Product.ExternalID)
+			} else {
+				unidentified++
+			}
+			continue
 		}
 
-		product := models.Product{Name: productName}
-		var category []models.Category
+		product := collected.Product
+		seen = append(seen, product.ExternalID)
 
-		category = append(category, models.Category{Name: categoryName})
-		err = models.AddProduct(db, &product, category)
+		var outcome string
+		err = models.WithTx(ctx, db, func(tx *sql.Tx) error {
+			outcome, err = models.SyncProduct(ctx, tx, &product, collected.Categories, options)
+			return err
+		})
 		if err != nil {
+			log.Error("Error saving product", "product", product.Name, "error", err)
+			summary.Failed++
 			continue
 		}
+
+		switch outcome {
+		case models.SyncInserted:
+			summary.Inserted++
+		case models.SyncUpdated:
+			summary.Updated++
+		case models.SyncAdopted:
+			summary.Updated++
+			summary.Adopted++
+		default:
+			summary.Unchanged++
+		}
+	}
+
+	// Empty feed is more likely upstream failure than removal of every product,
+	// and record that cannot be identified may belong to any of products that seem missing
+	if unidentified > 0 {
+		log.Warn("Records without external ID could not be mapped, missing products are not discontinued", "records", unidentified)
+	}
+	if len(seen) > 0 && unidentified == 0 {
+		var removed int64
+		err = models.WithTx(ctx, db, func(tx *sql.Tx) error {
+			removed, err = models.DiscontinueMissingProducts(ctx, tx, source.Name(), seen)
+			return err
+		})
+		if err != nil {
+			log.Error("Error marking discontinued products", "error", err)
+			return summary, err
+		}
+		summary.Removed = int(removed)
 	}
 
-	fmt.Println("Products collected and saved successfully")
-	return nil
+	log.Info("Products collected", "inserted", summary.Inserted, "updated", summary.Updated, "adopted", summary.Adopted,
+		"unchanged", summary.Unchanged, "removed", summary.Removed, "failed", summary.Failed)
+	return summary, nil
 }
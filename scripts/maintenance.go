@@ -0,0 +1,82 @@
+package scripts
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupPrefix and backupSuffix surround timestamp in names of database backup files
+const (
+	backupPrefix = "catalog-"
+	backupSuffix = ".db"
+)
+
+// CleanupCollectorRuns deletes history of collection runs older than retention period
+// returns number of deleted runs and any error encountered
+func CleanupCollectorRuns(ctx context.Context, db *sql.DB, retention time.Duration) (int64, error) {
+	deleted, err := models.DeleteCollectorRunsBefore(ctx, db, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+
+	logger.FromContext(ctx).Info("Deleted old collector runs", "count", deleted, "retention", retention)
+	return deleted, nil
+}
+
+// BackupDatabase writes consistent copy of database into new timestamped file in specified directory
+// Only keep most recent backup files are left in directory, zero keep leaves all of them
+// returns path of created backup and any error encountered
+func BackupDatabase(ctx context.Context, db *sql.DB, dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+
+	// VACUUM INTO produces compacted snapshot without blocking writers for long
+	path := filepath.Join(dir, backupPrefix+time.Now().UTC().Format("20060102T150405.000Z")+backupSuffix)
+	if _, err := db.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		logger.FromContext(ctx).Error("Error backing up database", "error", err)
+		return "", err
+	}
+	logger.FromContext(ctx).Info("Database backed up", "path", path)
+
+	if keep > 0 {
+		if err := pruneBackups(dir, keep); err != nil {
+			return path, fmt.Errorf("pruning old backups: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// pruneBackups removes all but keep most recent backup files in directory
+func pruneBackups(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	// Timestamps in names make lexical order chronological
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix) {
+			backups = append(backups, name)
+		}
+	}
+	sort.Strings(backups)
+
+	for len(backups) > keep {
+		if err := os.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
@@ -1,72 +1,495 @@
 package scripts
 
 import (
+	"context"
 	"database/sql"
+	"embed"
+	"fmt"
 	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// Migrate migrates database schema by creating necessary tables if they do not exist
+// migrationFiles holds numbered SQL migrations, each version has NNNN_name.up.sql and NNNN_name.down.sql file
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationFilePattern matches migration file names and captures version, name and direction
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// requiresPattern matches directive on first line of up file naming SQLite compile option required by migration
+var requiresPattern = regexp.MustCompile(`^-- requires: (\w+)`)
+
+// Migration represents single versioned schema change
+// Requires names SQLite compile option, such as ENABLE_FTS5, without which migration is skipped
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Requires string
+}
+
+// MigrationStatus describes whether migration has been applied to database
+// Skipped migration requires compile option SQLite is built without, so it is not pending
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	Skipped   bool
+	AppliedAt time.Time
+}
+
+// legacyColumns lists columns added to tables of databases created before versioned migrations were introduced
+var legacyColumns = []struct{ table, column, definition string }{
+	{"categories", "parent_id", "INTEGER REFERENCES categories(id)"},
+	{"products", "price", "INTEGER NOT NULL DEFAULT 0"},
+	{"products", "currency", "TEXT NOT NULL DEFAULT 'USD'"},
+	{"products", "description", "TEXT NOT NULL DEFAULT ''"},
+	{"products", "sku", "TEXT"},
+	{"products", "created_at", "DATETIME"},
+	{"products", "updated_at", "DATETIME"},
+	{"users", "role", "TEXT NOT NULL DEFAULT 'viewer'"},
+}
+
+// Migrate brings database schema to latest version by applying all pending migrations
 // takes *sql.DB parameter representing database connection
-// If any error occurs during table creation, logs error and returns it
-// Otherwise logs successful creation of each table and returns nil
+// If any error occurs, logs error and returns it
 func Migrate(db *sql.DB) error {
-	_, err := db.Exec(`
-        CREATE TABLE IF NOT EXISTS categories (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            name TEXT UNIQUE 
-        )
-    `)
+	return MigrateUp(db)
+}
+
+// MigrateUp applies all pending migrations in order of their versions
+func MigrateUp(db *sql.DB) error {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		return nil
+	}
+	return MigrateTo(db, migrations[len(migrations)-1].Version)
+}
+
+// MigrateDown reverts most recently applied migration
+// Does nothing if no migrations are applied
+func MigrateDown(db *sql.DB) error {
+	current, err := CurrentVersion(db)
 	if err != nil {
-		logger.Println("Error creating 'categories' table:", err)
 		return err
 	}
+	if current == 0 {
+		logger.Info("No migrations to revert")
+		return nil
+	}
 
-	logger.Println("Table 'categories' created successfully")
+	// Find highest version below current one
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+	target := 0
+	for _, migration := range migrations {
+		if migration.Version < current {
+			target = migration.Version
+		}
+	}
+	return MigrateTo(db, target)
+}
 
-	_, err = db.Exec(`
-        CREATE TABLE IF NOT EXISTS products (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            name TEXT UNIQUE 
-        )
-    `)
+// MigrateTo applies or reverts migrations until database schema has specified version
+// Version 0 reverts all migrations
+// Each migration runs in its own transaction together with its record in 'schema_migrations' table
+func MigrateTo(db *sql.DB, version int) error {
+	migrations, err := LoadMigrations()
 	if err != nil {
-		logger.Println("Error creating 'products' table:", err)
 		return err
 	}
 
-	logger.Println("Table 'products' created successfully")
+	// Check that target version exists
+	if version != 0 && findMigration(migrations, version) == nil {
+		return fmt.Errorf("migration version %d does not exist", version)
+	}
+
+	if err := ensureMigrationsTable(db); err != nil {
+		logger.Error("Error creating 'schema_migrations' table", "error", err)
+		return err
+	}
+
+	// Prepare database created before versioned migrations were introduced
+	if err := baselineLegacySchema(db); err != nil {
+		logger.Error("Error upgrading legacy schema", "error", err)
+		return err
+	}
 
-	_, err = db.Exec(`
-	   CREATE TABLE IF NOT EXISTS product_categories (
-	       product_id INTEGER,
-	       category_id INTEGER,
-	       FOREIGN KEY(product_id) REFERENCES products(id),
-	       FOREIGN KEY(category_id) REFERENCES categories(id),
-	       PRIMARY KEY (product_id, category_id)
-	   )
-	`)
+	applied, err := appliedVersions(context.Background(), db)
 	if err != nil {
-		logger.Println("Error creating 'product_categories' table:", err)
 		return err
 	}
 
-	logger.Println("Table 'product_categories' created successfully")
+	// Apply pending migrations up to target version in ascending order
+	for _, migration := range migrations {
+		if migration.Version > version || applied[migration.Version] {
+			continue
+		}
+		supported, err := isSupported(context.Background(), db, migration)
+		if err != nil {
+			return err
+		}
+		if !supported {
+			logger.Warn("Migration skipped, SQLite is built without required option",
+				"version", migration.Version, "name", migration.Name, "requires", migration.Requires)
+			continue
+		}
+		if err := applyMigration(db, migration, true); err != nil {
+			logger.Error("Error applying migration", "version", migration.Version, "name", migration.Name, "error", err)
+			return err
+		}
+		logger.Info("Migration applied", "version", migration.Version, "name", migration.Name)
+	}
+
+	// Revert applied migrations above target version in descending order
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version <= version || !applied[migration.Version] {
+			continue
+		}
+		if err := applyMigration(db, migration, false); err != nil {
+			logger.Error("Error reverting migration", "version", migration.Version, "name", migration.Name, "error", err)
+			return err
+		}
+		logger.Info("Migration reverted", "version", migration.Version, "name", migration.Name)
+	}
+
+	return nil
+}
+
+// CurrentVersion returns highest applied migration version, 0 if no migrations are applied
+func CurrentVersion(db *sql.DB) (int, error) {
+	if err := ensureMigrationsTable(db); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		logger.Error("Error getting current schema version", "error", err)
+		return 0, err
+	}
+	return version, nil
+}
+
+// PendingMigrations returns highest applied migration version and migrations that are not applied yet
+// Migrations skipped because SQLite lacks required option are not pending
+// Unlike CurrentVersion and GetMigrationStatus it only reads database, so it is safe to call from health checks;
+// database without 'schema_migrations' table has no migrations applied
+func PendingMigrations(ctx context.Context, db *sql.DB) (int, []Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, nil, err
+	}
 
-	_, err = db.Exec(`
-        CREATE TABLE IF NOT EXISTS users (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            username TEXT,
-            password TEXT
+	var tables int
+	err = db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&tables)
+	if err != nil {
+		return 0, nil, err
+	}
+	applied := make(map[int]bool)
+	if tables > 0 {
+		if applied, err = appliedVersions(ctx, db); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	current := 0
+	var pending []Migration
+	for _, migration := range migrations {
+		if applied[migration.Version] {
+			current = migration.Version
+			continue
+		}
+		supported, err := isSupported(ctx, db, migration)
+		if err != nil {
+			return 0, nil, err
+		}
+		if supported {
+			pending = append(pending, migration)
+		}
+	}
+	return current, pending, nil
+}
+
+// GetMigrationStatus lists all known migrations together with information whether they are applied
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	// Read application time of applied migrations
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		logger.Error("Error querying 'schema_migrations' table", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			logger.Error("Error scanning 'schema_migrations' row", "error", err)
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Combine known migrations with their application time
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		at, applied := appliedAt[migration.Version]
+		supported, err := isSupported(context.Background(), db, migration)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   applied,
+			Skipped:   !applied && !supported,
+			AppliedAt: at,
+		})
+	}
+	return statuses, nil
+}
+
+// LoadMigrations reads embedded migration files and returns migrations sorted by version
+// Returns error if file name does not follow naming scheme or if version lacks up or down file
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		// Parse version, name and direction from file name
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+			if requires := requiresPattern.FindStringSubmatch(migration.Up); requires != nil {
+				migration.Requires = requires[1]
+			}
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	// Check that every migration can be applied and reverted
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration version %d must have both up and down files", migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// applyMigration runs up or down script of migration and records result in 'schema_migrations' table
+// Both happen in single transaction, so failed migration leaves no trace
+func applyMigration(db *sql.DB, migration Migration, up bool) (err error) {
+	// Begin transaction
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	if up {
+		if _, err = tx.Exec(migration.Up); err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+			migration.Version, migration.Name, time.Now().UTC())
+		return err
+	}
+
+	if _, err = tx.Exec(migration.Down); err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	return err
+}
+
+// ensureMigrationsTable creates 'schema_migrations' table if it does not exist
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at DATETIME NOT NULL
         )
     `)
+	return err
+}
+
+// appliedVersions returns set of versions recorded in 'schema_migrations' table
+func appliedVersions(ctx context.Context, db *sql.DB) (map[int]bool, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
 	if err != nil {
-		logger.Println("Error creating 'users' table:", err)
-		return err
+		logger.Error("Error querying 'schema_migrations' table", "error", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	logger.Println("Table 'users' created successfully")
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
 
+// findMigration returns migration with specified version or nil if there is none
+func findMigration(migrations []Migration, version int) *Migration {
+	for i := range migrations {
+		if migrations[i].Version == version {
+			return &migrations[i]
+		}
+	}
 	return nil
 }
+
+// baselineLegacySchema upgrades database created by unversioned migrations, recognized by
+// existing 'products' table and empty 'schema_migrations' table, to schema of first migration
+// Adds columns missing from old tables, so that first migration only has to create missing tables and indexes
+// Upgrade runs in single transaction, so database is either fully upgraded or left as it was
+func baselineLegacySchema(db *sql.DB) error {
+	ctx := context.Background()
+	return models.WithTx(ctx, db, func(tx *sql.Tx) error {
+		// Check if database has no recorded migrations but already has tables
+		var recorded, legacy int
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&recorded)
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'products'").Scan(&legacy)
+		if err != nil {
+			return err
+		}
+		if recorded > 0 || legacy == 0 {
+			return nil
+		}
+
+		logger.Info("Upgrading database created before versioned migrations")
+
+		// Add columns introduced after tables were first created
+		for _, column := range legacyColumns {
+			if err := addColumnIfNotExists(ctx, tx, column.table, column.column, column.definition); err != nil {
+				return fmt.Errorf("adding column '%s' to '%s' table: %w", column.column, column.table, err)
+			}
+		}
+
+		// Fill timestamps of products created before timestamps were tracked
+		_, err = tx.ExecContext(ctx, `
+            UPDATE products SET
+                created_at = COALESCE(created_at, CURRENT_TIMESTAMP),
+                updated_at = COALESCE(updated_at, CURRENT_TIMESTAMP)
+            WHERE created_at IS NULL OR updated_at IS NULL
+        `)
+		return err
+	})
+}
+
+// addColumnIfNotExists adds column with given definition to table unless table already has it
+// Does nothing if table does not exist
+// returns error if table information cannot be read or column cannot be added
+func addColumnIfNotExists(ctx context.Context, db models.Executor, table, column, definition string) error {
+	rows, err := db.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Look for column among existing columns of table
+	tableExists := false
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		tableExists = true
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !tableExists {
+		return nil
+	}
+
+	_, err = db.ExecContext(ctx, "ALTER TABLE "+table+" ADD COLUMN "+column+" "+definition)
+	return err
+}
+
+// isSupported checks if SQLite was compiled with option required by migration
+func isSupported(ctx context.Context, db *sql.DB, migration Migration) (bool, error) {
+	if migration.Requires == "" {
+		return true, nil
+	}
+	var used bool
+	err := db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used(?)", migration.Requires).Scan(&used)
+	return used, err
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS product_categories;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE,
+    parent_id INTEGER REFERENCES categories(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS products (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE,
+    price INTEGER NOT NULL DEFAULT 0,
+    currency TEXT NOT NULL DEFAULT 'USD',
+    description TEXT NOT NULL DEFAULT '',
+    sku TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_sku ON products (sku);
+
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id INTEGER,
+    category_id INTEGER,
+    FOREIGN KEY(product_id) REFERENCES products(id),
+    FOREIGN KEY(category_id) REFERENCES categories(id),
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username TEXT,
+    password TEXT,
+    role TEXT NOT NULL DEFAULT 'viewer'
+);
@@ -0,0 +1,14 @@
+CREATE TABLE product_categories_old (
+    product_id INTEGER,
+    category_id INTEGER,
+    FOREIGN KEY(product_id) REFERENCES products(id),
+    FOREIGN KEY(category_id) REFERENCES categories(id),
+    PRIMARY KEY (product_id, category_id)
+);
+
+INSERT INTO product_categories_old (product_id, category_id)
+SELECT product_id, category_id FROM product_categories;
+
+DROP TABLE product_categories;
+
+ALTER TABLE product_categories_old RENAME TO product_categories;
@@ -0,0 +1,17 @@
+-- Links are removed together with their product, while category with links cannot be deleted
+-- until its links are removed or reassigned. Dangling links left by earlier versions are dropped.
+CREATE TABLE product_categories_new (
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE RESTRICT,
+    PRIMARY KEY (product_id, category_id)
+);
+
+INSERT OR IGNORE INTO product_categories_new (product_id, category_id)
+SELECT product_id, category_id FROM product_categories
+WHERE product_id IN (SELECT id FROM products) AND category_id IN (SELECT id FROM categories);
+
+DROP TABLE product_categories;
+
+ALTER TABLE product_categories_new RENAME TO product_categories;
+
+CREATE INDEX idx_product_categories_category_id ON product_categories (category_id);
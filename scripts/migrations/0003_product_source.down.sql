@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_products_source_external_id;
+
+ALTER TABLE products DROP COLUMN discontinued_at;
+ALTER TABLE products DROP COLUMN external_id;
+ALTER TABLE products DROP COLUMN source;
@@ -0,0 +1,6 @@
+-- Collected products remember where they came from, so that later collections update them in place
+ALTER TABLE products ADD COLUMN source TEXT;
+ALTER TABLE products ADD COLUMN external_id TEXT;
+ALTER TABLE products ADD COLUMN discontinued_at DATETIME;
+
+CREATE UNIQUE INDEX idx_products_source_external_id ON products (source, external_id);
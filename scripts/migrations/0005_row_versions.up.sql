@@ -0,0 +1,3 @@
+-- Versions of products and categories grow with every change, clients send them back to avoid lost updates
+ALTER TABLE products ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE categories ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
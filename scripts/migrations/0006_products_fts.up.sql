@@ -0,0 +1,26 @@
+-- requires: ENABLE_FTS5
+-- Full-text search index over product names and descriptions, kept in sync with 'products' table by triggers.
+-- Skipped if SQLite is built without FTS5 (build tag sqlite_fts5), then product search is disabled.
+CREATE VIRTUAL TABLE IF NOT EXISTS products_fts USING fts5(
+    name, description,
+    content = 'products', content_rowid = 'id',
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS products_fts_insert AFTER INSERT ON products BEGIN
+    INSERT INTO products_fts (rowid, name, description) VALUES (new.id, new.name, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS products_fts_delete AFTER DELETE ON products BEGIN
+    INSERT INTO products_fts (products_fts, rowid, name, description)
+    VALUES ('delete', old.id, old.name, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS products_fts_update AFTER UPDATE OF name, description ON products BEGIN
+    INSERT INTO products_fts (products_fts, rowid, name, description)
+    VALUES ('delete', old.id, old.name, old.description);
+    INSERT INTO products_fts (rowid, name, description) VALUES (new.id, new.name, new.description);
+END;
+
+-- Index products that existed before search index was created
+INSERT INTO products_fts (products_fts) VALUES ('rebuild');
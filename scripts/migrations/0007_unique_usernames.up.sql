@@ -0,0 +1,5 @@
+-- Usernames identify users for login and role changes, so every username must belong to single user
+-- Duplicates registered before are removed, keeping user who registered first
+DELETE FROM users WHERE id NOT IN (SELECT MIN(id) FROM users GROUP BY username);
+
+CREATE UNIQUE INDEX idx_users_username ON users (username);
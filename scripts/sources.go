@@ -0,0 +1,388 @@
+package scripts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/MaximInnopolis/ProductCatalog/internal/httpclient"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/scheduler"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Built-in source endpoints
+const (
+	EmojiHubURL = "https://emojihub.yurace.pro/api/all"
+	PetstoreURL = "https://petstore.swagger.io/v2/pet/findByStatus?status=available"
+)
+
+// DefaultCollectionInterval is interval between collections from source without configured interval
+const DefaultCollectionInterval = time.Hour
+
+// outbound is HTTP client shared by all sources, guarded by outboundMu
+var (
+	outboundMu sync.RWMutex
+	outbound   = httpclient.New(httpclient.DefaultConfig())
+)
+
+// SetHTTPClient replaces HTTP client shared by all sources
+func SetHTTPClient(client *httpclient.Client) {
+	outboundMu.Lock()
+	defer outboundMu.Unlock()
+	outbound = client
+}
+
+// outboundClient returns HTTP client shared by all sources
+func outboundClient() *httpclient.Client {
+	outboundMu.RLock()
+	defer outboundMu.RUnlock()
+	return outbound
+}
+
+// Record is single raw item fetched from source
+type Record map[string]interface{}
+
+// CollectedProduct is product obtained from source together with its categories
+type CollectedProduct struct {
+	Product    models.Product
+	Categories []models.Category
+}
+
+// Source is external system products are collected from
+// Collection first fetches raw records and then maps each record into product with categories
+type Source interface {
+	// Name identifies source in logs
+	Name() string
+	// Fetch retrieves raw records from source
+	Fetch(ctx context.Context) ([]Record, error)
+	// Map converts raw record into product with its categories
+	// If record cannot be mapped, returned product should still carry external ID of record when it is known,
+	// so that product of record is not treated as missing from source
+	Map(record Record) (CollectedProduct, error)
+}
+
+// Adopter is implemented by sources that may take over products with same name and no source,
+// such as products created by hand or before sources were tracked
+type Adopter interface {
+	// AdoptsExisting reports whether source takes over such products
+	AdoptsExisting() bool
+}
+
+// ScheduledSource is source collected periodically on given schedule
+type ScheduledSource struct {
+	Source   Source
+	Schedule scheduler.Schedule
+	// RunOnStart collects source as soon as collection starts instead of waiting for first scheduled time
+	RunOnStart bool
+}
+
+// FieldMapping holds dotted paths of product attributes inside source record, for example "category.name"
+// ExternalID identifies record in source, product name is used when it is not set
+type FieldMapping struct {
+	ExternalID  string `json:"external_id"`
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	Price       string `json:"price"`
+	Currency    string `json:"currency"`
+	SKU         string `json:"sku"`
+}
+
+// SourceConfig describes source in collector configuration file
+type SourceConfig struct {
+	// Type is one of emojihub, petstore and json
+	Type string `json:"type"`
+	// Name overrides default name of source
+	Name string `json:"name"`
+	// URL overrides default endpoint of built-in source and is required for json source
+	URL string `json:"url"`
+	// Interval between collections, in time.ParseDuration format
+	Interval string `json:"interval"`
+	// Schedule is cron expression or predefined schedule such as "@daily", used instead of interval
+	Schedule string `json:"schedule"`
+	// RunOnStart collects source right after server starts
+	RunOnStart bool `json:"run_on_start"`
+	// ItemsPath is dotted path to array of records in response, empty if response itself is array
+	ItemsPath string `json:"items_path"`
+	// Fields maps product attributes to record fields of json source
+	Fields FieldMapping `json:"fields"`
+	// PriceScale multiplies source price to get minor currency units, 1 if not set
+	PriceScale float64 `json:"price_scale"`
+	// AdoptExisting lets source take over products with same name and no source, off by default,
+	// meant to be turned on once when catalog created before sources were tracked is handed over to source
+	AdoptExisting bool `json:"adopt_existing"`
+}
+
+// JSONSource collects products from HTTP endpoint returning JSON array of records
+// Field mapping decides which record fields become product name, categories and other attributes
+type JSONSource struct {
+	name       string
+	url        string
+	itemsPath  string
+	fields     FieldMapping
+	priceScale float64
+	adopt      bool
+}
+
+// NewJSONSource creates source reading records from endpoint described by configuration
+func NewJSONSource(config SourceConfig) (*JSONSource, error) {
+	if config.URL == "" {
+		return nil, errors.New("json source requires url")
+	}
+	if config.Fields.Name == "" || config.Fields.Category == "" {
+		return nil, errors.New("json source requires name and category field mapping")
+	}
+
+	name := config.Name
+	if name == "" {
+		name = config.URL
+	}
+	priceScale := config.PriceScale
+	if priceScale == 0 {
+		priceScale = 1
+	}
+
+	return &JSONSource{
+		name:       name,
+		url:        config.URL,
+		itemsPath:  config.ItemsPath,
+		fields:     config.Fields,
+		priceScale: priceScale,
+		adopt:      config.AdoptExisting,
+	}, nil
+}
+
+// NewEmojiHubSource creates source collecting emojis from emojihub, each emoji category becomes product category
+func NewEmojiHubSource() *JSONSource {
+	source, _ := NewJSONSource(SourceConfig{
+		Name:   "emojihub",
+		URL:    EmojiHubURL,
+		Fields: FieldMapping{Name: "name", Category: "category"},
+	})
+	return source
+}
+
+// NewPetstoreSource creates source collecting available pets from Swagger petstore
+func NewPetstoreSource() *JSONSource {
+	source, _ := NewJSONSource(SourceConfig{
+		Name:   "petstore",
+		URL:    PetstoreURL,
+		Fields: FieldMapping{ExternalID: "id", Name: "name", Category: "category.name"},
+	})
+	return source
+}
+
+// Name returns name of source
+func (s *JSONSource) Name() string {
+	return s.name
+}
+
+// AdoptsExisting reports whether source takes over products with same name and no source
+func (s *JSONSource) AdoptsExisting() bool {
+	return s.adopt
+}
+
+// Fetch sends HTTP request to source endpoint through shared outbound client and decodes records from response
+func (s *JSONSource) Fetch(ctx context.Context) ([]Record, error) {
+	response, err := outboundClient().Get(ctx, s.name, s.url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("source %s responded with status %s", s.name, response.Status)
+	}
+
+	// Decode response and locate array of records in it
+	var body interface{}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	items, ok := lookupPath(body, s.itemsPath).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("source %s response has no array at %q", s.name, s.itemsPath)
+	}
+
+	// Keep only items that are JSON objects
+	records := make([]Record, 0, len(items))
+	for _, item := range items {
+		if record, ok := item.(map[string]interface{}); ok {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// Map converts record into product according to field mapping of source
+// Category field may hold single name or array of names
+func (s *JSONSource) Map(record Record) (CollectedProduct, error) {
+	var collected CollectedProduct
+
+	// Record is identified by external ID, or by its name if source has no IDs
+	// Identity is set first, so that it is known even if rest of record is invalid
+	name, _ := lookupPath(map[string]interface{}(record), s.fields.Name).(string)
+	collected.Product.Source = s.name
+	collected.Product.ExternalID = s.stringField(record, s.fields.ExternalID)
+	if collected.Product.ExternalID == "" {
+		collected.Product.ExternalID = strings.TrimSpace(name)
+	}
+
+	// Product name is required
+	if strings.TrimSpace(name) == "" {
+		return collected, errors.New("product name not found in source")
+	}
+	collected.Product.Name = name
+
+	// At least one category is required
+	switch category := lookupPath(map[string]interface{}(record), s.fields.Category).(type) {
+	case string:
+		if category != "" {
+			collected.Categories = append(collected.Categories, models.Category{Name: category})
+		}
+	case []interface{}:
+		for _, item := range category {
+			if name, ok := item.(string); ok && name != "" {
+				collected.Categories = append(collected.Categories, models.Category{Name: name})
+			}
+		}
+	}
+	if len(collected.Categories) == 0 {
+		return collected, errors.New("category name not found in source")
+	}
+
+	// Optional attributes
+	collected.Product.Description = s.stringField(record, s.fields.Description)
+	collected.Product.Currency = s.stringField(record, s.fields.Currency)
+	collected.Product.SKU = s.stringField(record, s.fields.SKU)
+	if s.fields.Price != "" {
+		if price, ok := lookupPath(map[string]interface{}(record), s.fields.Price).(float64); ok {
+			collected.Product.Price = int64(math.Round(price * s.priceScale))
+		}
+	}
+
+	return collected, nil
+}
+
+// stringField returns value at path in record formatted as string, or empty string if path is not set
+func (s *JSONSource) stringField(record Record, path string) string {
+	if path == "" {
+		return ""
+	}
+	switch value := lookupPath(map[string]interface{}(record), path).(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	default:
+		return ""
+	}
+}
+
+// lookupPath follows dotted path of object keys starting from value
+// Empty path returns value itself, missing key returns nil
+func lookupPath(value interface{}, path string) interface{} {
+	if path == "" {
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// NewSource creates source described by configuration
+func NewSource(config SourceConfig) (Source, error) {
+	var source *JSONSource
+	switch config.Type {
+	case "emojihub":
+		source = NewEmojiHubSource()
+	case "petstore":
+		source = NewPetstoreSource()
+	case "json":
+		return NewJSONSource(config)
+	default:
+		return nil, fmt.Errorf("unknown source type %q", config.Type)
+	}
+
+	// Built-in sources accept overridden name and endpoint
+	if config.Name != "" {
+		source.name = config.Name
+	}
+	if config.URL != "" {
+		source.url = config.URL
+	}
+	source.adopt = config.AdoptExisting
+	return source, nil
+}
+
+// LoadSources reads collector configuration file containing JSON array of source configurations
+// Empty path returns default configuration, which collects emojihub every hour
+func LoadSources(path string) ([]ScheduledSource, error) {
+	configs := []SourceConfig{{Type: "emojihub"}}
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		configs = nil
+		if err := json.Unmarshal(content, &configs); err != nil {
+			return nil, fmt.Errorf("parsing collector configuration %s: %w", path, err)
+		}
+	}
+
+	return NewScheduledSources(configs)
+}
+
+// NewScheduledSources creates sources with their collection schedules from configurations
+// Source without interval and schedule is collected every DefaultCollectionInterval
+// Names of sources must be unique, as products and runs of source are identified by its name
+func NewScheduledSources(configs []SourceConfig) ([]ScheduledSource, error) {
+	sources := make([]ScheduledSource, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+	for i, config := range configs {
+		source, err := NewSource(config)
+		if err != nil {
+			return nil, fmt.Errorf("source %d: %w", i, err)
+		}
+
+		// Check that no other source has same name
+		if names[source.Name()] {
+			return nil, fmt.Errorf("source %d: duplicate source name %q", i, source.Name())
+		}
+		names[source.Name()] = true
+
+		var schedule scheduler.Schedule = scheduler.Every(DefaultCollectionInterval)
+		switch {
+		case config.Interval != "" && config.Schedule != "":
+			return nil, fmt.Errorf("source %s: interval and schedule are mutually exclusive", source.Name())
+		case config.Interval != "":
+			interval, err := time.ParseDuration(config.Interval)
+			if err != nil {
+				return nil, fmt.Errorf("source %s: invalid interval: %w", source.Name(), err)
+			}
+			if interval <= 0 {
+				return nil, fmt.Errorf("source %s: interval must be positive", source.Name())
+			}
+			schedule = scheduler.Every(interval)
+		case config.Schedule != "":
+			schedule, err = scheduler.Parse(config.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("source %s: invalid schedule: %w", source.Name(), err)
+			}
+		}
+
+		sources = append(sources, ScheduledSource{Source: source, Schedule: schedule, RunOnStart: config.RunOnStart})
+	}
+	return sources, nil
+}
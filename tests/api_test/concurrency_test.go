@@ -0,0 +1,180 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// heldBody is request body that delivers its data at once, but holds connection open afterwards
+// until release is closed, like slow client, so that all requests are being served at same time
+type heldBody struct {
+	data    *bytes.Reader
+	arrived *sync.WaitGroup
+	release chan struct{}
+	once    sync.Once
+}
+
+// Read returns data of body, then waits for release before reporting end of body
+func (b *heldBody) Read(p []byte) (int, error) {
+	if b.data.Len() > 0 {
+		return b.data.Read(p)
+	}
+	b.once.Do(b.arrived.Done)
+	<-b.release
+	return 0, io.EOF
+}
+
+// Close does nothing, as body holds no resources
+func (b *heldBody) Close() error {
+	return nil
+}
+
+// TestConcurrentProductRequests sends create and update requests in parallel and checks that
+// none of them fails with server error and every product ends up with exactly data and categories of its own request
+// Run with -race to detect shared state in handlers
+func TestConcurrentProductRequests(t *testing.T) {
+	// File database with production connection settings and pool, so that requests really run concurrently
+	require.NoError(t, database.Init(filepath.Join(t.TempDir(), "catalog.db")))
+	defer database.Close()
+	db := database.GetDB()
+	require.NoError(t, scripts.Migrate(db))
+
+	auth.Configure(config.AuthConfig{SecretKey: strings.Repeat("k", config.MinSecretKeyLength)})
+	token := userToken(t, db, "editor", models.RoleEditor)
+
+	// Products updated by test exist beforehand in shared category
+	const requests = 25
+	ctx := context.Background()
+	for i := 0; i < requests; i++ {
+		product := &models.Product{Name: fmt.Sprintf("Updated %d", i)}
+		require.NoError(t, models.AddProduct(ctx, db, product, []models.Category{{Name: "Initial"}}))
+	}
+
+	handler := api.NewHandler(config.Default().Server)
+	var arrived sync.WaitGroup
+	arrived.Add(2 * requests)
+	release := make(chan struct{})
+	var serverErrors atomic.Int32
+	send := func(method, path string, body interface{}) int {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return 0
+		}
+		req := httptest.NewRequest(method, path, nil)
+		req.Body = &heldBody{data: bytes.NewReader(payload), arrived: &arrived, release: release}
+		req.Header.Set("Authorization", "Bearer "+token)
+		// Updated products have not been changed since they were created
+		req.Header.Set("If-Match", `"1"`)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code >= http.StatusInternalServerError {
+			serverErrors.Add(1)
+		}
+		return rr.Code
+	}
+
+	// Requests differ in every field, and every second request sends no description,
+	// so that data of one request showing up in another is detected
+	productBody := func(name string, i int) map[string]interface{} {
+		categories := []map[string]string{{"name": fmt.Sprintf("Own %d", i)}}
+		if i%3 == 0 {
+			categories = append(categories, map[string]string{"name": fmt.Sprintf("Extra %d", i)})
+		}
+		body := map[string]interface{}{
+			"Name":       name,
+			"Price":      int64(i * 100),
+			"Categories": categories,
+		}
+		if i%2 == 0 {
+			body["Description"] = fmt.Sprintf("Description %d", i)
+		}
+		return body
+	}
+
+	var wg sync.WaitGroup
+	statuses := make(chan string, 2*requests)
+	start := make(chan struct{})
+	for i := 0; i < requests; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if status := send(http.MethodPost, "/products/new", productBody(fmt.Sprintf("Created %d", i), i)); status != http.StatusCreated {
+				statuses <- fmt.Sprintf("create %d: status %d", i, status)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if status := send(http.MethodPut, "/products", productBody(fmt.Sprintf("Updated %d", i), i)); status != http.StatusOK {
+				statuses <- fmt.Sprintf("update %d: status %d", i, status)
+			}
+		}(i)
+	}
+	close(start)
+
+	// Let requests finish once all of them have read their bodies, or after timeout if some never read it
+	allArrived := make(chan struct{})
+	go func() {
+		arrived.Wait()
+		close(allArrived)
+	}()
+	select {
+	case <-allArrived:
+	case <-time.After(10 * time.Second):
+		t.Error("Not every request read its body")
+	}
+	close(release)
+	wg.Wait()
+	close(statuses)
+	for status := range statuses {
+		t.Error(status)
+	}
+	assert.Zero(t, serverErrors.Load(), "requests failed with server error")
+
+	// Every product holds data of its own request only
+	products, total, err := models.GetProducts(ctx, db, &models.ProductListOptions{Limit: 4 * requests})
+	require.NoError(t, err)
+	require.Equal(t, 2*requests, total)
+	for _, product := range products {
+		var i int
+		var kind string
+		_, err := fmt.Sscanf(product.Name, "%s %d", &kind, &i)
+		require.NoError(t, err, product.Name)
+
+		assert.Equal(t, int64(i*100), product.Price, product.Name)
+		expectedDescription := ""
+		if i%2 == 0 {
+			expectedDescription = fmt.Sprintf("Description %d", i)
+		}
+		assert.Equal(t, expectedDescription, product.Description, product.Name)
+
+		details, err := models.GetProduct(ctx, db, int64(product.ID))
+		require.NoError(t, err)
+		expectedCategories := []string{fmt.Sprintf("Own %d", i)}
+		if i%3 == 0 {
+			expectedCategories = append(expectedCategories, fmt.Sprintf("Extra %d", i))
+		}
+		assert.ElementsMatch(t, expectedCategories, details.Categories, product.Name)
+	}
+}
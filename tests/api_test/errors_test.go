@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorResponses(t *testing.T) {
+	require.NoError(t, database.Init(":memory:"))
+	defer database.Close()
+	db := database.GetDB()
+	db.SetMaxOpenConns(1)
+	require.NoError(t, scripts.Migrate(db))
+
+	auth.Configure(config.AuthConfig{SecretKey: strings.Repeat("k", config.MinSecretKeyLength)})
+	token := userToken(t, db, "admin", models.RoleAdmin)
+
+	ctx := context.Background()
+	require.NoError(t, models.AddProduct(ctx, db, &models.Product{Name: "Rex"}, []models.Category{{Name: "Dogs"}}))
+	require.NoError(t, models.RegisterUser(ctx, db, &models.User{Username: "alice", Password: "secret-password"}))
+
+	handler := api.NewHandler(config.Default().Server)
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		auth   bool
+		status int
+		code   string
+	}{
+		{"missing product", http.MethodGet, "/products/42", "", false, http.StatusNotFound, models.CodeProductNotFound},
+		{"missing product by name", http.MethodDelete, "/products/by-name/Ghost", "", true, http.StatusNotFound, models.CodeProductNotFound},
+		{"missing category", http.MethodPut, "/categories/Ghosts", `{"name":"Spirits"}`, true, http.StatusNotFound, models.CodeCategoryNotFound},
+		{"duplicate category", http.MethodPost, "/categories/new", `{"name":"Dogs"}`, true, http.StatusConflict, models.CodeCategoryExists},
+		{"category with products", http.MethodDelete, "/categories/Dogs", "", true, http.StatusConflict, models.CodeCategoryNotEmpty},
+		{"reserved category name", http.MethodPost, "/categories/new", `{"name":"tree"}`, true, http.StatusUnprocessableEntity, models.CodeReservedCategoryName},
+		{"reserved product category name", http.MethodPost, "/products/new", `{"Name":"Tom","Categories":[{"name":"list"}]}`, true, http.StatusUnprocessableEntity, models.CodeReservedCategoryName},
+		{"duplicate product", http.MethodPost, "/products/new", `{"Name":"Rex","Categories":[{"name":"Dogs"}]}`, true, http.StatusConflict, models.CodeProductExists},
+		{"product without categories", http.MethodPost, "/products/new", `{"Name":"Tom","Categories":[]}`, true, http.StatusUnprocessableEntity, models.CodeValidationFailed},
+		{"negative price", http.MethodPost, "/products/new", `{"Name":"Tom","Price":-1,"Categories":[{"name":"Cats"}]}`, true, http.StatusUnprocessableEntity, models.CodeValidationFailed},
+		{"unsupported sort", http.MethodGet, "/products?sort=color", "", false, http.StatusUnprocessableEntity, models.CodeInvalidSort},
+		{"malformed body", http.MethodPost, "/categories/new", `{`, true, http.StatusBadRequest, models.CodeMalformedBody},
+		{"wrong password", http.MethodPost, "/auth/login", `{"username":"alice","password":"wrong-password"}`, false, http.StatusUnauthorized, models.CodeInvalidCredentials},
+		{"unknown user", http.MethodPost, "/auth/login", `{"username":"bob","password":"secret-password"}`, false, http.StatusUnauthorized, models.CodeInvalidCredentials},
+		{"taken username", http.MethodPost, "/auth/register", `{"username":"alice","password":"secret-password"}`, false, http.StatusConflict, models.CodeUsernameTaken},
+		{"missing authorization", http.MethodPost, "/categories/new", `{"name":"Cats"}`, false, http.StatusUnauthorized, models.CodeMissingAuthorization},
+		{"unknown route", http.MethodGet, "/no/such/route", "", false, http.StatusNotFound, models.CodeRouteNotFound},
+		{"unsupported method", http.MethodPost, "/categories/list", "", false, http.StatusMethodNotAllowed, models.CodeMethodNotAllowed},
+		{"product ID out of range", http.MethodGet, "/products/99999999999999999999", "", false, http.StatusNotFound, models.CodeProductNotFound},
+		{"non-integer limit", http.MethodGet, "/products?limit=ten", "", false, http.StatusBadRequest, models.CodeInvalidParameter},
+		{"unknown order", http.MethodGet, "/products?order=up", "", false, http.StatusBadRequest, models.CodeInvalidParameter},
+		{"non-boolean flag", http.MethodGet, "/categories/Dogs/products?recursive=maybe", "", false, http.StatusBadRequest, models.CodeInvalidParameter},
+		{"missing search text", http.MethodGet, "/search?q=", "", false, http.StatusBadRequest, models.CodeInvalidParameter},
+		{"non-integer search limit", http.MethodGet, "/search?q=rex&limit=ten", "", false, http.StatusBadRequest, models.CodeInvalidParameter},
+		{"non-integer run limit", http.MethodGet, "/admin/collector/runs?limit=ten", "", true, http.StatusBadRequest, models.CodeInvalidParameter},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			if tc.auth {
+				req.Header.Set("Authorization", "Bearer "+token)
+				// Changes of any version are allowed, see TestConditionalRequests for version checks
+				req.Header.Set("If-Match", "*")
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.status, rr.Code)
+			assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+			var problem models.Problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+			assert.Equal(t, tc.status, problem.Status)
+			assert.Equal(t, http.StatusText(tc.status), problem.Title)
+			assert.Equal(t, tc.code, problem.Code)
+			assert.NotEmpty(t, problem.Detail)
+			assert.NotContains(t, problem.Detail, "strconv")
+			assert.Equal(t, strings.SplitN(tc.path, "?", 2)[0], problem.Instance)
+		})
+	}
+}
+
+// userToken registers user with specified role in database and logs in, returning token of user
+// Roles are checked against database, so tokens of users that do not exist are rejected
+func userToken(t *testing.T, db *sql.DB, username, role string) string {
+	t.Helper()
+	ctx := context.Background()
+	user := &models.User{Username: username, Password: "secret-password"}
+	require.NoError(t, models.RegisterUser(ctx, db, user))
+	require.NoError(t, models.SetUserRole(ctx, db, username, role))
+	token, err := models.LoginUser(ctx, db, user)
+	require.NoError(t, err)
+	return token
+}
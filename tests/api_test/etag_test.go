@@ -0,0 +1,194 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	require.NoError(t, database.Init(":memory:"))
+	defer database.Close()
+	db := database.GetDB()
+	db.SetMaxOpenConns(1)
+	require.NoError(t, scripts.Migrate(db))
+
+	auth.Configure(config.AuthConfig{SecretKey: strings.Repeat("k", config.MinSecretKeyLength)})
+	token := userToken(t, db, "admin", models.RoleAdmin)
+
+	ctx := context.Background()
+	product := &models.Product{Name: "Rex"}
+	require.NoError(t, models.AddProduct(ctx, db, product, []models.Category{{Name: "Dogs"}}))
+
+	handler := api.NewHandler(config.Default().Server)
+	serve := func(method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	problemCode := func(rr *httptest.ResponseRecorder) string {
+		var problem models.Problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		return problem.Code
+	}
+	productPath := "/products/" + strconv.Itoa(product.ID)
+	productBody := func(price int) string {
+		body, err := json.Marshal(map[string]interface{}{"Name": "Rex", "Price": price, "Categories": []map[string]string{{"name": "Dogs"}}})
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("product", func(t *testing.T) {
+		// Reads carry entity tag and honor If-None-Match
+		rr := serve(http.MethodGet, productPath, "", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		etag := rr.Header().Get("ETag")
+		assert.Equal(t, `"1"`, etag)
+
+		rr = serve(http.MethodGet, productPath, "", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.Bytes())
+		assert.Equal(t, etag, rr.Header().Get("ETag"))
+		rr = serve(http.MethodGet, productPath, "", map[string]string{"If-None-Match": `"7", W/` + etag})
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+
+		// Changes require If-Match
+		rr = serve(http.MethodPut, "/products", productBody(100), nil)
+		assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+		assert.Equal(t, models.CodeVersionRequired, problemCode(rr))
+		rr = serve(http.MethodDelete, "/products/by-name/Rex", "", nil)
+		assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+
+		// First editor wins, second one holding same version is rejected
+		rr = serve(http.MethodPut, "/products", productBody(100), map[string]string{"If-Match": etag})
+		require.Equal(t, http.StatusOK, rr.Code)
+		newETag := rr.Header().Get("ETag")
+		assert.Equal(t, `"2"`, newETag)
+
+		rr = serve(http.MethodPut, "/products", productBody(200), map[string]string{"If-Match": etag})
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		assert.Equal(t, models.CodeVersionMismatch, problemCode(rr))
+
+		// Old entity tag no longer matches on read, so client gets current product
+		rr = serve(http.MethodGet, productPath, "", map[string]string{"If-None-Match": etag})
+		require.Equal(t, http.StatusOK, rr.Code)
+		var details models.ProductDetails
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &details))
+		assert.Equal(t, int64(100), details.Price)
+		assert.Equal(t, int64(2), details.Version)
+
+		// Weak entity tags never match If-Match, ambiguous lists are rejected
+		rr = serve(http.MethodDelete, "/products/by-name/Rex", "", map[string]string{"If-Match": "W/" + newETag})
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		rr = serve(http.MethodDelete, "/products/by-name/Rex", "", map[string]string{"If-Match": `"1", "2"`})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, models.CodeInvalidVersion, problemCode(rr))
+
+		rr = serve(http.MethodDelete, "/products/by-name/Rex", "", map[string]string{"If-Match": etag})
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		rr = serve(http.MethodDelete, "/products/by-name/Rex", "", map[string]string{"If-Match": newETag})
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("product by ID", func(t *testing.T) {
+		tom := &models.Product{Name: "Tom"}
+		require.NoError(t, models.AddProduct(ctx, db, tom, []models.Category{{Name: "Cats"}}))
+		tomPath := "/products/" + strconv.Itoa(tom.ID)
+		body := `{"Name":"Tom II","Price":300,"Categories":[{"name":"Cats"}]}`
+
+		// Product addressed by ID can be renamed
+		rr := serve(http.MethodPut, tomPath, body, nil)
+		assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+		rr = serve(http.MethodPut, tomPath, body, map[string]string{"If-Match": `"1"`})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+		details, err := models.GetProduct(ctx, db, int64(tom.ID))
+		require.NoError(t, err)
+		assert.Equal(t, "Tom II", details.Name)
+		assert.Equal(t, int64(300), details.Price)
+
+		// Missing products are not found, whatever name request body holds
+		for _, path := range []string{"/products/0", "/products/999"} {
+			rr = serve(http.MethodPut, path, body, map[string]string{"If-Match": "*"})
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+			assert.Equal(t, models.CodeProductNotFound, problemCode(rr))
+			rr = serve(http.MethodDelete, path, "", map[string]string{"If-Match": "*"})
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+			assert.Equal(t, models.CodeProductNotFound, problemCode(rr))
+		}
+
+		rr = serve(http.MethodDelete, tomPath, "", map[string]string{"If-Match": `"1"`})
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		rr = serve(http.MethodDelete, tomPath, "", map[string]string{"If-Match": `"2"`})
+		assert.Equal(t, http.StatusOK, rr.Code)
+		rr = serve(http.MethodGet, tomPath, "", nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("product with numeric name", func(t *testing.T) {
+		novel := &models.Product{Name: "1984"}
+		require.NoError(t, models.AddProduct(ctx, db, novel, []models.Category{{Name: "Books"}}))
+
+		// Product is deleted by its name, not by ID spelled the same way
+		rr := serve(http.MethodDelete, "/products/by-name/1984", "", map[string]string{"If-Match": `"1"`})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		_, err := models.GetProduct(ctx, db, int64(novel.ID))
+		assert.ErrorIs(t, err, models.ErrNotFound)
+	})
+
+	t.Run("category", func(t *testing.T) {
+		rr := serve(http.MethodGet, "/categories/Dogs", "", nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var category models.Category
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &category))
+		assert.Equal(t, "Dogs", category.Name)
+		etag := rr.Header().Get("ETag")
+		assert.Equal(t, `"1"`, etag)
+
+		rr = serve(http.MethodGet, "/categories/Dogs", "", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+
+		rr = serve(http.MethodPut, "/categories/Dogs", `{"name":"Hounds"}`, nil)
+		assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+		rr = serve(http.MethodPut, "/categories/Dogs", `{"name":"Hounds"}`, map[string]string{"If-Match": `"5"`})
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		rr = serve(http.MethodPut, "/categories/Dogs", `{"name":"Hounds"}`, map[string]string{"If-Match": etag})
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+		rr = serve(http.MethodPut, "/categories/Hounds/parent", `{"parent":""}`, map[string]string{"If-Match": etag})
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+
+		// Move returns new entity tag, so client can go on changing category without reading it again
+		rr = serve(http.MethodPut, "/categories/Hounds/parent", `{"parent":""}`, map[string]string{"If-Match": `"2"`})
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+
+		rr = serve(http.MethodDelete, "/categories/Hounds", "", map[string]string{"If-Match": `"2"`})
+		assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+		rr = serve(http.MethodDelete, "/categories/Hounds", "", map[string]string{"If-Match": `"3"`})
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = serve(http.MethodGet, "/categories/Hounds", "", nil)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
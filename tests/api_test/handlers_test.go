@@ -19,7 +19,7 @@ func TestRegisterHandlers(t *testing.T) {
 
 	// Start test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		api.RegisterHandlers()
+		api.NewRouter().ServeHTTP(w, r)
 	}))
 	defer server.Close()
 
@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// probe requests probe endpoint and decodes its response
+func probe(t *testing.T, path string) (int, api.ProbeResponse) {
+	rr := httptest.NewRecorder()
+	api.NewRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var response api.ProbeResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	return rr.Code, response
+}
+
+func TestLivenessProbe(t *testing.T) {
+	code, response := probe(t, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, api.StatusOK, response.Status)
+	assert.Empty(t, response.Components)
+	assert.NotEmpty(t, response.Build.Version)
+	assert.NotEmpty(t, response.Build.GoVersion)
+}
+
+func TestReadinessProbe(t *testing.T) {
+	require.NoError(t, database.Init(":memory:"))
+	defer database.Close()
+	db := database.GetDB()
+	db.SetMaxOpenConns(1)
+	require.NoError(t, scripts.Migrate(db))
+
+	// Migrated database is ready
+	code, response := probe(t, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, api.StatusOK, response.Status)
+	assert.Equal(t, api.StatusOK, response.Components["database"].Status)
+	assert.Equal(t, api.StatusOK, response.Components["migrations"].Status)
+	assert.Equal(t, float64(0), response.Components["migrations"].Details["pending"])
+	assert.NotContains(t, response.Components, "collector")
+
+	// Failed collection degrades readiness only if enabled
+	run, err := models.StartCollectorRun(context.Background(), db, "emojihub")
+	require.NoError(t, err)
+	require.NoError(t, models.FinishCollectorRun(context.Background(), db, run, errors.New("upstream is down")))
+
+	code, response = probe(t, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, api.StatusOK, response.Status)
+
+	api.SetDegradeOnCollectorFailure(true)
+	defer api.SetDegradeOnCollectorFailure(false)
+	code, response = probe(t, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, api.StatusDegraded, response.Status)
+	assert.Equal(t, api.StatusDegraded, response.Components["collector"].Status)
+	assert.Equal(t, "upstream is down", response.Components["collector"].Error)
+
+	// Pending migration makes service unavailable
+	current, err := scripts.CurrentVersion(db)
+	require.NoError(t, err)
+	require.NoError(t, scripts.MigrateTo(db, current-1))
+	code, response = probe(t, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, api.StatusDown, response.Status)
+	assert.Equal(t, api.StatusDown, response.Components["migrations"].Status)
+	assert.Equal(t, float64(1), response.Components["migrations"].Details["pending"])
+
+	// Unreachable database makes service unavailable
+	require.NoError(t, db.Close())
+	code, response = probe(t, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, api.StatusDown, response.Components["database"].Status)
+}
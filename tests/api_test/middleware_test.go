@@ -0,0 +1,126 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs makes logger write JSON records to temporary file
+// returned function closes file and decodes records written so far
+func captureLogs(t *testing.T) func() []map[string]interface{} {
+	path := filepath.Join(t.TempDir(), "app.log")
+	err := logger.Configure(config.LogConfig{Level: "info", Format: config.LogFormatJSON, Outputs: []string{path}})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+
+	return func() []map[string]interface{} {
+		require.NoError(t, logger.Close())
+		file, err := os.Open(path)
+		require.NoError(t, err)
+		defer file.Close()
+
+		var records []map[string]interface{}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var record map[string]interface{}
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func TestRequestIDPropagated(t *testing.T) {
+	var seen string
+	handler := api.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = api.RequestID(r.Context())
+	}), api.WithRequestID)
+
+	// ID sent by client is kept
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(api.RequestIDHeader, "client-id.42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, "client-id.42", seen)
+	assert.Equal(t, "client-id.42", rr.Header().Get(api.RequestIDHeader))
+
+	// Missing or malformed ID is replaced by generated one
+	for _, id := range []string{"", "bad id\nwith newline", strings.Repeat("x", 200)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(api.RequestIDHeader, id)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.NotEmpty(t, seen)
+		assert.NotEqual(t, id, seen)
+		assert.Equal(t, seen, rr.Header().Get(api.RequestIDHeader))
+	}
+}
+
+func TestRecoverFromPanic(t *testing.T) {
+	readLogs := captureLogs(t)
+	handler := api.Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("broken handler")
+	}), api.WithRequestID, api.AccessLog(config.AccessLogJSON), api.Recover)
+
+	req := httptest.NewRequest(http.MethodGet, "/products?limit=5", nil)
+	req.Header.Set(api.RequestIDHeader, "panic-1")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	// Client gets problem response instead of dropped connection
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type":"about:blank","title":"Internal Server Error","status":500,
+		"detail":"internal server error","instance":"/products","code":"internal_error"}`, rr.Body.String())
+
+	// Panic is logged with stack trace and request is still logged by access log
+	records := readLogs()
+	require.Len(t, records, 2)
+	assert.Equal(t, "Handler panicked", records[0]["msg"])
+	assert.Equal(t, "broken handler", records[0]["panic"])
+	assert.Contains(t, records[0]["stack"], "middleware_test.go")
+	assert.Equal(t, "panic-1", records[0]["request_id"])
+
+	assert.Equal(t, "Request served", records[1]["msg"])
+	assert.Equal(t, float64(http.StatusInternalServerError), records[1]["status"])
+	assert.Equal(t, "/products?limit=5", records[1]["path"])
+	assert.Equal(t, "panic-1", records[1]["request_id"])
+}
+
+func TestAccessLogFormats(t *testing.T) {
+	readLogs := captureLogs(t)
+	handler := api.NewHandler(config.Default().Server)
+
+	// Combined format by default, unmatched routes are logged too
+	req := httptest.NewRequest(http.MethodGet, "/no/such/route", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	records := readLogs()
+	require.Len(t, records, 1)
+	line := records[0]["msg"].(string)
+	assert.Contains(t, line, fmt.Sprintf(`"GET /no/such/route HTTP/1.1" 404 %d "-" "test-agent"`, rr.Body.Len()))
+	assert.Equal(t, rr.Header().Get(api.RequestIDHeader), records[0]["request_id"])
+
+	// Access log can be disabled
+	readLogs = captureLogs(t)
+	serverConfig := config.Default().Server
+	serverConfig.AccessLog = config.AccessLogOff
+	api.NewHandler(serverConfig).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/no/such/route", nil))
+	assert.Empty(t, readLogs())
+}
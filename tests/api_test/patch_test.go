@@ -0,0 +1,171 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/patch"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatchRequests(t *testing.T) {
+	require.NoError(t, database.Init(":memory:"))
+	defer database.Close()
+	db := database.GetDB()
+	db.SetMaxOpenConns(1)
+	require.NoError(t, scripts.Migrate(db))
+
+	auth.Configure(config.AuthConfig{SecretKey: strings.Repeat("k", config.MinSecretKeyLength)})
+	token := userToken(t, db, "editor", models.RoleEditor)
+
+	ctx := context.Background()
+	product := &models.Product{Name: "Rex", Price: 100, Description: "Good dog"}
+	require.NoError(t, models.AddProduct(ctx, db, product, []models.Category{{Name: "Dogs"}, {Name: "Pets"}}))
+	_, err := models.AddCategory(ctx, db, &models.Category{Name: "Puppies", Parent: "Dogs"})
+	require.NoError(t, err)
+
+	handler := api.NewHandler(config.Default().Server)
+	send := func(path, mediaType, version, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", mediaType)
+		if version != "" {
+			req.Header.Set("If-Match", version)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	problemCode := func(rr *httptest.ResponseRecorder) string {
+		var problem models.Problem
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		return problem.Code
+	}
+	productPath := "/products/" + strconv.Itoa(product.ID)
+	current := func() models.ProductDetails {
+		details, err := models.GetProduct(ctx, db, int64(product.ID))
+		require.NoError(t, err)
+		return *details
+	}
+
+	t.Run("merge patch of product", func(t *testing.T) {
+		rr := send(productPath, patch.MergePatchType, `"1"`,
+			`{"price": 150, "description": null, "categories": {"Pets": null, "Puppies": true}}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+		var details models.ProductDetails
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &details))
+		assert.Equal(t, "Rex", details.Name)
+		assert.Equal(t, int64(150), details.Price)
+		assert.Empty(t, details.Description)
+		assert.ElementsMatch(t, []string{"Dogs", "Puppies"}, details.Categories)
+		assert.Equal(t, details, current())
+	})
+
+	t.Run("JSON patch of product", func(t *testing.T) {
+		rr := send(productPath, patch.JSONPatchType+"; charset=utf-8", `"2"`, `[
+			{"op": "test", "path": "/price", "value": 150},
+			{"op": "add", "path": "/categories/Good dogs", "value": true},
+			{"op": "remove", "path": "/categories/Puppies"},
+			{"op": "replace", "path": "/name", "value": "Rex II"}
+		]`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, `"3"`, rr.Header().Get("ETag"))
+
+		details := current()
+		assert.Equal(t, "Rex II", details.Name)
+		assert.ElementsMatch(t, []string{"Dogs", "Good dogs"}, details.Categories)
+	})
+
+	t.Run("rejected patches change nothing", func(t *testing.T) {
+		before := current()
+		tests := []struct {
+			name, mediaType, version, body string
+			status                         int
+			code                           string
+		}{
+			{"failed test", patch.JSONPatchType, `"3"`,
+				`[{"op": "replace", "path": "/price", "value": 1}, {"op": "test", "path": "/sku", "value": "X"}]`,
+				http.StatusConflict, models.CodePatchConflict},
+			{"missing location", patch.JSONPatchType, `"3"`, `[{"op": "remove", "path": "/categories/Cats"}]`,
+				http.StatusConflict, models.CodePatchConflict},
+			{"no categories left", patch.MergePatchType, `"3"`, `{"price": 1, "categories": {"Dogs": null, "Good dogs": null}}`,
+				http.StatusUnprocessableEntity, models.CodeValidationFailed},
+			{"negative price", patch.MergePatchType, `"3"`, `{"price": -1}`,
+				http.StatusUnprocessableEntity, models.CodeValidationFailed},
+			{"read-only field", patch.MergePatchType, `"3"`, `{"id": 7}`,
+				http.StatusUnprocessableEntity, models.CodeInvalidPatch},
+			{"category set of wrong type", patch.MergePatchType, `"3"`, `{"categories": ["Cats"]}`,
+				http.StatusUnprocessableEntity, models.CodeInvalidPatch},
+			{"field name of wrong case", patch.MergePatchType, `"3"`, `{"Name": "Tom"}`,
+				http.StatusUnprocessableEntity, models.CodeInvalidPatch},
+			{"added field name of wrong case", patch.JSONPatchType, `"3"`, `[{"op": "add", "path": "/Price", "value": 1}]`,
+				http.StatusUnprocessableEntity, models.CodeInvalidPatch},
+			{"index into object", patch.JSONPatchType, `"3"`, `[{"op": "remove", "path": "/price/0"}]`,
+				http.StatusConflict, models.CodePatchConflict},
+			{"unknown operation", patch.JSONPatchType, `"3"`, `[{"op": "drop", "path": "/sku"}]`,
+				http.StatusBadRequest, models.CodeInvalidPatch},
+			{"plain JSON", "application/json", `"3"`, `{"price": 1}`,
+				http.StatusUnsupportedMediaType, models.CodeUnsupportedMediaType},
+			{"missing version", patch.MergePatchType, "", `{"price": 1}`,
+				http.StatusPreconditionRequired, models.CodeVersionRequired},
+			{"stale version", patch.MergePatchType, `"2"`, `{"price": 1}`,
+				http.StatusPreconditionFailed, models.CodeVersionMismatch},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				rr := send(productPath, test.mediaType, test.version, test.body)
+				assert.Equal(t, test.status, rr.Code)
+				assert.Equal(t, test.code, problemCode(rr))
+				if test.status == http.StatusUnsupportedMediaType {
+					assert.Equal(t, patch.MergePatchType+", "+patch.JSONPatchType, rr.Header().Get("Accept-Patch"))
+				}
+				assert.Equal(t, before, current())
+			})
+		}
+	})
+
+	t.Run("missing product", func(t *testing.T) {
+		rr := send("/products/999", patch.MergePatchType, "*", `{"price": 1}`)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("patch of category", func(t *testing.T) {
+		// Rename and move in single request
+		rr := send("/categories/Puppies", patch.MergePatchType, `"1"`, `{"name": "Young dogs", "parent": null}`)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var category models.Category
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &category))
+		assert.Equal(t, "Young dogs", category.Name)
+		assert.Empty(t, category.Parent)
+		assert.Equal(t, `"`+strconv.FormatInt(category.Version, 10)+`"`, rr.Header().Get("ETag"))
+
+		// Move that would create cycle fails, so rename in same patch is not applied either
+		rr = send("/categories/Dogs", patch.JSONPatchType, "*",
+			`[{"op": "replace", "path": "/name", "value": "Hounds"}, {"op": "add", "path": "/parent", "value": "Dogs"}]`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, models.CodeCategoryCycle, problemCode(rr))
+		_, err := models.GetCategory(ctx, db, "Dogs")
+		assert.NoError(t, err)
+
+		rr = send("/categories/Dogs", patch.MergePatchType, "*", `{"name": ""}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		rr = send("/categories/Dogs", patch.MergePatchType, "*", `{"name": "Young dogs"}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, models.CodeCategoryExists, problemCode(rr))
+	})
+}
@@ -0,0 +1,144 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestValidation(t *testing.T) {
+	require.NoError(t, database.Init(":memory:"))
+	defer database.Close()
+	db := database.GetDB()
+	db.SetMaxOpenConns(1)
+	require.NoError(t, scripts.Migrate(db))
+
+	auth.Configure(config.AuthConfig{SecretKey: strings.Repeat("k", config.MinSecretKeyLength)})
+	token := userToken(t, db, "editor", models.RoleEditor)
+
+	serverConfig := config.Default().Server
+	serverConfig.MaxBodyBytes = 1024
+	handler := api.NewHandler(serverConfig)
+	serve := func(method, path, body string) (*httptest.ResponseRecorder, models.Problem) {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var problem models.Problem
+		if rr.Code >= http.StatusBadRequest {
+			assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		}
+		return rr, problem
+	}
+
+	t.Run("every violation is reported", func(t *testing.T) {
+		body := `{"Name":"   ","Price":-5,"Currency":"usd","SKU":"has space","Categories":[{"name":""},{"name":"Dogs"}]}`
+		rr, problem := serve(http.MethodPost, "/products/new", body)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, models.CodeValidationFailed, problem.Code)
+		fields := make(map[string]string)
+		for _, violation := range problem.Errors {
+			fields[violation.Field] = violation.Code
+		}
+		assert.Equal(t, map[string]string{
+			"Name":               "required",
+			"Price":              "too_small",
+			"Currency":           "invalid_characters",
+			"SKU":                "invalid_characters",
+			"Categories[0].name": "required",
+		}, fields)
+	})
+
+	t.Run("at least one category is required", func(t *testing.T) {
+		rr, problem := serve(http.MethodPost, "/products/new", `{"Name":"Rex","Categories":[]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, models.Violation{Field: "Categories", Code: "required", Message: "is required"}, problem.Errors[0])
+	})
+
+	t.Run("repeated categories are associated once", func(t *testing.T) {
+		rr, _ := serve(http.MethodPost, "/products/new", `{"Name":"Rex","Categories":[{"name":"Dogs"},{"name":"Dogs"}]}`)
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+		// Update repeats both existing and new category
+		req := httptest.NewRequest(http.MethodPut, "/products",
+			bytes.NewBufferString(`{"Name":"Rex","Categories":[{"name":"Dogs"},{"name":"Pets"},{"name":"Dogs"},{"name":"Pets"}]}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", "*")
+		rr = httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		productID, err := models.GetProductID(context.Background(), db, "Rex")
+		require.NoError(t, err)
+		categories, err := models.GetCategoriesByProductID(context.Background(), db, productID)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"Dogs", "Pets"}, categories)
+	})
+
+	t.Run("names are trimmed and normalized", func(t *testing.T) {
+		rr, _ := serve(http.MethodPost, "/categories/new", `{"name":"  Café  "}`)
+		require.Equal(t, http.StatusCreated, rr.Code)
+
+		_, err := models.GetCategoryID(context.Background(), db, "Café")
+		assert.NoError(t, err)
+	})
+
+	t.Run("long names are rejected", func(t *testing.T) {
+		rr, problem := serve(http.MethodPost, "/categories/new", `{"name":"`+strings.Repeat("x", 101)+`"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, "too_long", problem.Errors[0].Code)
+	})
+
+	t.Run("unknown fields are rejected", func(t *testing.T) {
+		rr, problem := serve(http.MethodPost, "/categories/new", `{"name":"Cats","id":7}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, models.CodeMalformedBody, problem.Code)
+		assert.Contains(t, problem.Detail, `"id"`)
+	})
+
+	t.Run("trailing data is rejected", func(t *testing.T) {
+		rr, problem := serve(http.MethodPost, "/categories/new", `{"name":"Cats"}{"name":"Dogs"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, models.CodeMalformedBody, problem.Code)
+	})
+
+	t.Run("empty password is rejected", func(t *testing.T) {
+		rr, problem := serve(http.MethodPost, "/auth/register", `{"username":"alice","password":""}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		require.Len(t, problem.Errors, 1)
+		assert.Equal(t, "password", problem.Errors[0].Field)
+	})
+
+	t.Run("large body is rejected", func(t *testing.T) {
+		rr, problem := serve(http.MethodPost, "/categories/new", `{"name":"`+strings.Repeat("x", 2048)+`"}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, models.CodeBodyTooLarge, problem.Code)
+	})
+
+	t.Run("large body without length is rejected", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/categories/new", bytes.NewBufferString(`{"name":"`+strings.Repeat("x", 2048)+`"}`))
+		req.ContentLength = -1
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	})
+}
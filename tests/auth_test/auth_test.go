@@ -1,101 +1,101 @@
 package auth_test
 
 import (
-	"database/sql"
-	"errors"
-	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
-	"github.com/MaximInnopolis/ProductCatalog/internal/utils"
+	"context"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
 	"net/http"
 	"net/http/httptest"
-	"strings"
 	"testing"
 
 	"github.com/MaximInnopolis/ProductCatalog/internal/auth"
 	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
 	"github.com/stretchr/testify/assert"
 )
 
-// tokenValidator interface represents function for token validity check
-type tokenValidator interface {
-	IsTokenValid(db *sql.DB, tokenString string) (bool, error)
-}
-
-// mockTokenValidator implements tokenValidator interface for mocking IsTokenValid function
-type mockTokenValidator struct{}
-
-// IsTokenValid always returns true without error
-func (m *mockTokenValidator) IsTokenValid(db *sql.DB, tokenString string) (bool, error) {
-	return true, nil
-}
-
-func TestRequireValidToken_Success(t *testing.T) {
-	// Create valid token
-	validToken := "valid_token"
-
-	// Mock HTTP request with valid token in Authorization header
-	req, err := http.NewRequest("GET", "/", nil)
-	if err != nil {
+func TestRequireRole(t *testing.T) {
+	// Roles are read from database, so users must exist there
+	if err := database.Init(":memory:"); err != nil {
 		t.Fatal(err)
 	}
-	req.Header.Set("Authorization", "Bearer "+validToken)
-
-	// Mock HTTP response recorder
-	rr := httptest.NewRecorder()
-
-	// Mock database
-	database.Init(":memory:")
 	defer database.Close()
-
-	mockValidator := &mockTokenValidator{}
-
-	// Execute function
-	authenticated := RequireValidTokenMocked(rr, req, mockValidator)
-
-	// Check if authenticated is true
-	assert.True(t, authenticated)
-}
-
-func TestRequireValidToken_Failure(t *testing.T) {
-	// Mock HTTP request without Authorization header
-	req, err := http.NewRequest("GET", "/", nil)
-	if err != nil {
+	db := database.GetDB()
+	db.SetMaxOpenConns(1)
+	if err := scripts.Migrate(db); err != nil {
 		t.Fatal(err)
 	}
 
-	// Mock HTTP response recorder
-	rr := httptest.NewRecorder()
-
-	// Execute function
-	authenticated := auth.RequireValidToken(rr, req)
-
-	// Check if authenticated is false
-	assert.False(t, authenticated)
-
-	// Check status code in response
-	assert.Equal(t, http.StatusInternalServerError, rr.Code)
-}
-
-func RequireValidTokenMocked(w http.ResponseWriter, r *http.Request, validator tokenValidator) bool {
-	// Extract token from Authorization header
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		logger.Println("Authorization header is missing")
-
-		utils.WriteErrorJSONResponse(w, errors.New("authorization header is missing"), http.StatusInternalServerError)
-		return false
+	// Handler reached only when access is granted
+	handler := auth.RequireRole(models.RoleEditor, func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := auth.ClaimsFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "editor", claims.Username)
+		assert.Equal(t, models.RoleEditor, claims.Role)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Register users with different roles and log them in
+	ctx := context.Background()
+	login := func(username, role string) string {
+		user := &models.User{Username: username, Password: "secret-password"}
+		if err := models.RegisterUser(ctx, db, user); err != nil {
+			t.Fatal(err)
+		}
+		if err := models.SetUserRole(ctx, db, username, role); err != nil {
+			t.Fatal(err)
+		}
+		token, err := models.LoginUser(ctx, db, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
 	}
-	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	editorToken := login("editor", models.RoleEditor)
+	viewerToken := login("viewer", models.RoleViewer)
+	demotedToken := login("demoted", models.RoleEditor)
+	removedToken := login("removed", models.RoleEditor)
 
-	authenticated, err := validator.IsTokenValid(database.GetDB(), tokenString)
-	if err != nil {
-		utils.WriteErrorJSONResponse(w, err, http.StatusInternalServerError)
-		return false
+	// Role of token no longer matches role in database
+	if err := models.SetUserRole(ctx, db, "demoted", models.RoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DELETE FROM users WHERE username = ?", "removed"); err != nil {
+		t.Fatal(err)
 	}
 
-	if !authenticated {
-		utils.WriteErrorJSONResponse(w, errors.New("unauthorized"), http.StatusUnauthorized)
-		return false
+	testCases := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{"missing token", "", http.StatusUnauthorized, models.CodeMissingAuthorization},
+		{"invalid token", "Bearer invalid", http.StatusUnauthorized, models.CodeInvalidToken},
+		{"insufficient role", "Bearer " + viewerToken, http.StatusForbidden, models.CodeForbidden},
+		{"role revoked after login", "Bearer " + demotedToken, http.StatusForbidden, models.CodeForbidden},
+		{"user removed after login", "Bearer " + removedToken, http.StatusUnauthorized, models.CodeInvalidToken},
+		{"sufficient role", "Bearer " + editorToken, http.StatusNoContent, ""},
 	}
 
-	return true
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("POST", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedCode != "" {
+				assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+				assert.Contains(t, rr.Body.String(), `"code":"`+tc.expectedCode+`"`)
+				assert.Contains(t, rr.Body.String(), `"instance":"/"`)
+			}
+		})
+	}
 }
@@ -0,0 +1,144 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validSecret = "0123456789abcdef0123456789abcdef"
+
+func TestLoadPrecedence(t *testing.T) {
+	// File overrides defaults
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+database:
+  path: /var/lib/catalog/file.db
+server:
+  addr: ":9000"
+  write_timeout: 1m
+collector:
+  http_retries: 5
+backup:
+  dir: /backups
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+
+	// Environment overrides file, flags override environment
+	t.Setenv("SECRET_KEY", validSecret)
+	t.Setenv("HTTP_ADDR", ":9100")
+	t.Setenv("COLLECTOR_HTTP_RETRIES", "7")
+	t.Setenv("HTTP_MAX_BODY_BYTES", "4096")
+
+	cfg, args, err := config.Load("test", []string{"-config", path, "-addr", ":9200", "-backup-keep", "3", "up"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up"}, args)
+
+	assert.Equal(t, "/var/lib/catalog/file.db", cfg.Database.Path)
+	assert.Equal(t, time.Minute, cfg.Server.WriteTimeout)
+	assert.Equal(t, "/backups", cfg.Backup.Dir)
+	assert.Equal(t, validSecret, cfg.Auth.SecretKey)
+	assert.Equal(t, 7, cfg.Collector.HTTPRetries)
+	assert.Equal(t, int64(4096), cfg.Server.MaxBodyBytes)
+	assert.Equal(t, ":9200", cfg.Server.Addr)
+	assert.Equal(t, 3, cfg.Backup.Keep)
+
+	// Settings nobody set keep defaults
+	assert.Equal(t, config.Default().Server.ReadTimeout, cfg.Server.ReadTimeout)
+	assert.Equal(t, 24*time.Hour, cfg.Auth.TokenTTL)
+	require.NoError(t, cfg.Validate())
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("auth:\n  token_ttl: 2h\n"), 0600))
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, _, err := config.Load("test", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, cfg.Auth.TokenTTL)
+}
+
+func TestLoadRejectsInvalidInput(t *testing.T) {
+	// Unknown keys in file are rejected, so that typos do not go unnoticed
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  adress: \":9000\"\n"), 0600))
+	_, _, err := config.Load("test", []string{"-config", path})
+	assert.Error(t, err)
+
+	// Malformed values in environment and flags are rejected
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("HTTP_READ_TIMEOUT", "soon")
+		_, _, err := config.Load("test", nil)
+		assert.ErrorContains(t, err, "HTTP_READ_TIMEOUT")
+	})
+	_, _, err = config.Load("test", []string{"-backup-keep", "many"})
+	assert.ErrorContains(t, err, "backup-keep")
+	_, _, err = config.Load("test", []string{"-no-such-flag"})
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.SecretKey = validSecret
+	require.NoError(t, cfg.Validate())
+
+	// Secret key is required and must be long enough
+	cfg.Auth.SecretKey = ""
+	assert.ErrorContains(t, cfg.Validate(), "secret key is required")
+	cfg.Auth.SecretKey = "short"
+	assert.ErrorContains(t, cfg.Validate(), "at least 32 characters")
+	cfg.Auth.SecretKey = validSecret
+
+	// Admin password follows rules of registration
+	cfg.Auth.AdminUsername = "admin"
+	cfg.Auth.AdminPassword = "short"
+	assert.ErrorContains(t, cfg.Validate(), "admin password must be 8 to 72 characters long")
+	cfg.Auth.AdminPassword = strings.Repeat("p", 73)
+	assert.ErrorContains(t, cfg.Validate(), "admin password must be 8 to 72 characters long")
+	cfg.Auth.AdminPassword = "adminpassword"
+	require.NoError(t, cfg.Validate())
+	cfg.Auth.SecretKey = "short"
+
+	// Every problem is reported at once
+	cfg.Server.TLSCertFile = "cert.pem"
+	cfg.Collector.HTTPRetries = -1
+	cfg.Server.MaxBodyBytes = 0
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Equal(t, 4, len(strings.Split(err.Error(), "\n")))
+}
+
+func TestLoadLogSettings(t *testing.T) {
+	// Outputs are comma-separated list, blank items are dropped
+	t.Setenv("LOG_OUTPUTS", "stderr, /var/log/catalog.log,")
+	t.Setenv("LOG_COMPRESS", "true")
+	cfg, _, err := config.Load("test", []string{"-log-level", "debug", "-log-format", "json", "-log-max-age", "24h"})
+	require.NoError(t, err)
+	assert.True(t, cfg.Log.Compress)
+	assert.Equal(t, 24*time.Hour, cfg.Log.MaxAge)
+	assert.Equal(t, []string{"stderr", "/var/log/catalog.log"}, cfg.Log.Outputs)
+	assert.Equal(t, "debug", cfg.Log.Level)
+	assert.Equal(t, config.LogFormatJSON, cfg.Log.Format)
+
+	// Unknown level and format are reported by Validate
+	cfg.Auth.SecretKey = validSecret
+	cfg.Log.Level = "verbose"
+	cfg.Log.Format = "xml"
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, "unknown log level")
+	assert.ErrorContains(t, err, "log format must be")
+}
+
+func TestExampleConfigFile(t *testing.T) {
+	// Example shipped with repository stays loadable
+	cfg, _, err := config.Load("test", []string{"-config", "../../configs/config.example.yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, "configs/sources.example.json", cfg.Collector.SourcesFile)
+}
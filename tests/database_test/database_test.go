@@ -36,3 +36,21 @@ func TestInitAndGetDB(t *testing.T) {
 		t.Fatalf("Expected result to be 1, got %d", result)
 	}
 }
+
+func TestInitEnablesForeignKeys(t *testing.T) {
+	err := database.Init(":memory:")
+	if err != nil {
+		t.Fatalf("Error initializing database: %v", err)
+	}
+	defer database.Close()
+
+	// Check that foreign key enforcement is turned on
+	var enabled int
+	err = database.GetDB().QueryRow("PRAGMA foreign_keys").Scan(&enabled)
+	if err != nil {
+		t.Fatalf("Error querying database: %v", err)
+	}
+	if enabled != 1 {
+		t.Fatal("Expected foreign keys to be enabled")
+	}
+}
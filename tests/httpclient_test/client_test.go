@@ -0,0 +1,123 @@
+package httpclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/httpclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testConfig returns settings with short delays to keep tests fast
+func testConfig() httpclient.Config {
+	return httpclient.Config{
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         5 * time.Millisecond,
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+	}
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	// Server fails twice before responding successfully
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := httpclient.New(testConfig())
+	response, err := client.Get(context.Background(), "test", server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// Client errors are returned to caller without retries
+	atomic.StoreInt32(&calls, 0)
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer notFound.Close()
+
+	response, err = client.Get(context.Background(), "test", notFound.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestClientTimeout(t *testing.T) {
+	// Server hangs longer than client waits for single attempt
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := testConfig()
+	config.Timeout = 20 * time.Millisecond
+	config.MaxRetries = 1
+	client := httpclient.New(config)
+
+	start := time.Now()
+	_, err := client.Get(context.Background(), "test", server.URL)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var calls, healthy int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	config := testConfig()
+	config.MaxRetries = 0
+	client := httpclient.New(config)
+
+	// Consecutive failures open breaker, which then rejects requests without sending them
+	for i := 0; i < config.FailureThreshold; i++ {
+		_, err := client.Get(context.Background(), "broken", server.URL)
+		require.Error(t, err)
+	}
+	_, err := client.Get(context.Background(), "broken", server.URL)
+	assert.ErrorIs(t, err, httpclient.ErrCircuitOpen)
+	assert.Equal(t, int32(config.FailureThreshold), atomic.LoadInt32(&calls))
+
+	// Breakers of other keys are independent
+	_, err = client.Get(context.Background(), "other", server.URL)
+	assert.NotErrorIs(t, err, httpclient.ErrCircuitOpen)
+
+	// After open timeout trial request closes breaker again
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(config.OpenTimeout)
+	response, err := client.Get(context.Background(), "broken", server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+	response, err = client.Get(context.Background(), "broken", server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+}
@@ -0,0 +1,142 @@
+package logger_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatedFiles returns names of rotated files next to log file app.log in dir
+func rotatedFiles(t *testing.T, dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, "app-*"))
+	require.NoError(t, err)
+	for i, match := range matches {
+		matches[i] = filepath.Base(match)
+	}
+	return matches
+}
+
+func TestFileRotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	file, err := logger.OpenFile(path, logger.FileOptions{MaxSize: 10, MaxBackups: 2})
+	require.NoError(t, err)
+
+	// Every line overflows file, so every write after first one rotates it
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := file.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, file.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(current))
+
+	// Only two most recent rotated files are kept
+	rotated := rotatedFiles(t, dir)
+	require.Len(t, rotated, 2)
+	for _, name := range rotated {
+		assert.True(t, strings.HasSuffix(name, ".log"), name)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, rotated[1]))
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(content))
+}
+
+func TestFileRotatesByAgeAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	file, err := logger.OpenFile(path, logger.FileOptions{MaxAge: 20 * time.Millisecond, Compress: true})
+	require.NoError(t, err)
+
+	_, err = file.Write([]byte("old\n"))
+	require.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	_, err = file.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	// Rotated file is replaced by its gzipped copy
+	rotated := rotatedFiles(t, dir)
+	require.Len(t, rotated, 1)
+	require.True(t, strings.HasSuffix(rotated[0], ".log.gz"), rotated[0])
+
+	compressed, err := os.Open(filepath.Join(dir, rotated[0]))
+	require.NoError(t, err)
+	defer compressed.Close()
+	reader, err := gzip.NewReader(compressed)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "old\n", string(content))
+}
+
+func TestFileReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	file, err := logger.OpenFile(path, logger.FileOptions{})
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = file.Write([]byte("before\n"))
+	require.NoError(t, err)
+
+	// External tool moves file away, records go to new file only after reopening
+	require.NoError(t, os.Rename(path, filepath.Join(dir, "moved.log")))
+	require.NoError(t, file.Reopen())
+	_, err = file.Write([]byte("after\n"))
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "after\n", string(content))
+	moved, err := os.ReadFile(filepath.Join(dir, "moved.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(moved))
+}
+
+func TestFileReopenFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	require.NoError(t, os.Mkdir(dir, 0750))
+	path := filepath.Join(dir, "app.log")
+	file, err := logger.OpenFile(path, logger.FileOptions{})
+	require.NoError(t, err)
+	defer file.Close()
+
+	// Directory of file is removed, so file cannot be opened again
+	require.NoError(t, os.RemoveAll(dir))
+	assert.Error(t, file.Reopen())
+
+	// Records still go to previously opened file
+	_, err = file.Write([]byte("after\n"))
+	assert.NoError(t, err)
+
+	// Once directory is back, file is reopened at its path
+	require.NoError(t, os.Mkdir(dir, 0750))
+	require.NoError(t, file.Reopen())
+	_, err = file.Write([]byte("reopened\n"))
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "reopened\n", string(content))
+}
+
+func TestFilePermissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	file, err := logger.OpenFile(path, logger.FileOptions{})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0007, "log file must not be accessible to others")
+}
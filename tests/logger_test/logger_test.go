@@ -0,0 +1,113 @@
+package logger_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/logger"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// configureJSON makes logger write JSON records of specified level to new file and returns path of file
+func configureJSON(t *testing.T, level string) string {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	err := logger.Configure(config.LogConfig{Level: level, Format: config.LogFormatJSON, Outputs: []string{path}})
+	require.NoError(t, err)
+	t.Cleanup(func() { logger.Close() })
+	return path
+}
+
+// readRecords closes log file and decodes all records written to it
+func readRecords(t *testing.T, path string) []map[string]interface{} {
+	require.NoError(t, logger.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestConfigureLevel(t *testing.T) {
+	path := configureJSON(t, "warn")
+
+	logger.Info("hidden")
+	logger.Warn("shown", "count", 3)
+
+	records := readRecords(t, path)
+	require.Len(t, records, 1)
+	assert.Equal(t, "shown", records[0]["msg"])
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, float64(3), records[0]["count"])
+}
+
+func TestConfigureRejectsInvalidSettings(t *testing.T) {
+	err := logger.Configure(config.LogConfig{Level: "loud", Format: config.LogFormatText, Outputs: []string{"stdout"}})
+	assert.Error(t, err)
+	err = logger.Configure(config.LogConfig{Level: "info", Format: "xml", Outputs: []string{"stdout"}})
+	assert.Error(t, err)
+}
+
+func TestContextLogger(t *testing.T) {
+	path := configureJSON(t, "info")
+
+	// Context without logger falls back to base logger
+	ctx := context.Background()
+	logger.FromContext(ctx).Info("plain")
+
+	// Attributes added to context are carried by every record logged through it
+	ctx = logger.With(ctx, "request_id", "abc")
+	ctx = logger.With(ctx, "user", "alice")
+	logger.FromContext(ctx).Info("scoped")
+
+	records := readRecords(t, path)
+	require.Len(t, records, 2)
+	assert.NotContains(t, records[0], "request_id")
+	assert.Equal(t, "abc", records[1]["request_id"])
+	assert.Equal(t, "alice", records[1]["user"])
+}
+
+func TestRequestRecordsCarryRequestContext(t *testing.T) {
+	require.NoError(t, database.Init(":memory:"))
+	defer database.Close()
+	database.GetDB().SetMaxOpenConns(1)
+	require.NoError(t, scripts.Migrate(database.GetDB()))
+
+	path := configureJSON(t, "debug")
+
+	// Model records logged while serving request carry request ID and route
+	req := httptest.NewRequest(http.MethodGet, "/products", nil)
+	rr := httptest.NewRecorder()
+	api.NewHandler(config.Default().Server).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var found bool
+	for _, record := range readRecords(t, path) {
+		if record["msg"] != "Got products" {
+			continue
+		}
+		found = true
+		assert.NotEmpty(t, record["request_id"])
+		assert.Equal(t, "/products", record["route"])
+		assert.Equal(t, http.MethodGet, record["method"])
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,101 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/api"
+	"github.com/MaximInnopolis/ProductCatalog/internal/config"
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/metrics"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/scheduler"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingSource always fails to fetch records
+type failingSource struct{}
+
+func (failingSource) Name() string { return "failing" }
+
+func (failingSource) Fetch(ctx context.Context) ([]scripts.Record, error) {
+	return nil, errors.New("upstream is down")
+}
+
+func (failingSource) Map(record scripts.Record) (scripts.CollectedProduct, error) {
+	return scripts.CollectedProduct{}, errors.New("unexpected record")
+}
+
+// scrape returns metrics exposed by handler in Prometheus text format
+func scrape(t *testing.T, handler http.Handler) string {
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	body, err := io.ReadAll(rr.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	require.NoError(t, database.Init(":memory:"))
+	defer database.Close()
+	db := database.GetDB()
+	db.SetMaxOpenConns(1)
+	require.NoError(t, scripts.Migrate(db))
+	require.NoError(t, metrics.RegisterDatabase(db))
+
+	ctx := context.Background()
+	require.NoError(t, models.AddProduct(ctx, db, &models.Product{Name: "Rex"}, []models.Category{{Name: "Dogs"}}))
+	require.NoError(t, models.RegisterUser(ctx, db, &models.User{Username: "alice", Password: "secret-password"}))
+
+	handler := api.NewHandler(config.Default().Server)
+	serve := func(method, path, body string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return rr.Code
+	}
+
+	// Requests are labelled by route template, unknown paths share single label
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, "/products/1", ""))
+	require.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/products/2", ""))
+	require.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/no/such/route", ""))
+
+	// Logins are counted by outcome
+	serve(http.MethodPost, "/auth/login", `{"username":"alice","password":"secret-password"}`)
+	serve(http.MethodPost, "/auth/login", `{"username":"alice","password":"wrong-password"}`)
+	serve(http.MethodPost, "/auth/login", `{"username":"bob","password":"secret-password"}`)
+
+	// Failed collection is counted with its duration
+	collector := scripts.NewCollector(db, []scripts.ScheduledSource{{Source: failingSource{}, Schedule: scheduler.Every(time.Hour)}})
+	require.NoError(t, collector.Trigger())
+	collector.Wait()
+
+	body := scrape(t, handler)
+	assert.Contains(t, body, `catalog_http_requests_total{method="GET",route="/products/{id:[0-9]+}",status="200"} 1`)
+	assert.Contains(t, body, `catalog_http_requests_total{method="GET",route="/products/{id:[0-9]+}",status="404"} 1`)
+	assert.Contains(t, body, `catalog_http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `catalog_http_request_duration_seconds_count{method="GET",route="/products/{id:[0-9]+}",status="200"} 1`)
+	assert.NotContains(t, body, `route="/products/1"`)
+
+	assert.Contains(t, body, `catalog_auth_logins_total{outcome="success"} 1`)
+	assert.Contains(t, body, `catalog_auth_logins_total{outcome="invalid_password"} 1`)
+	assert.Contains(t, body, `catalog_auth_logins_total{outcome="unknown_user"} 1`)
+
+	assert.Contains(t, body, `catalog_collector_runs_total{source="failing",status="failed"} 1`)
+	assert.Contains(t, body, `catalog_collector_run_duration_seconds_count{source="failing",status="failed"} 1`)
+
+	// Catalog size and connection pool are read from database at scrape time
+	assert.Contains(t, body, `catalog_products{state="active"} 1`)
+	assert.Contains(t, body, `catalog_products{state="discontinued"} 0`)
+	assert.Contains(t, body, `catalog_categories 1`)
+	assert.Contains(t, body, `catalog_counts_scrape_success 1`)
+	assert.Contains(t, body, `go_sql_max_open_connections{db_name="catalog"} 1`)
+}
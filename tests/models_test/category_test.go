@@ -1,6 +1,7 @@
 package models_test
 
 import (
+	"context"
 	"database/sql"
 	"github.com/MaximInnopolis/ProductCatalog/internal/models"
 	"testing"
@@ -24,7 +25,7 @@ func TestAddCategory(t *testing.T) {
 	category := &models.Category{Name: "Test Category"}
 
 	// Add category to database
-	categoryID, err := models.AddCategory(db, category)
+	categoryID, err := models.AddCategory(context.Background(), db, category)
 	if err != nil {
 		t.Fatalf("Error adding category: %v", err)
 	}
@@ -35,7 +36,7 @@ func TestAddCategory(t *testing.T) {
 	}
 
 	// Retrieve all categories from database
-	categories, err := models.GetAllCategories(db)
+	categories, err := models.GetAllCategories(context.Background(), db)
 	if err != nil {
 		t.Fatalf("Error retrieving categories: %v", err)
 	}
@@ -69,20 +70,20 @@ func TestUpdateCategory(t *testing.T) {
 	category := &models.Category{Name: "Test Category"}
 
 	// Add category to database
-	_, err = models.AddCategory(db, category)
+	_, err = models.AddCategory(context.Background(), db, category)
 	if err != nil {
 		t.Fatalf("Error adding category: %v", err)
 	}
 
 	// Update category name
 	newCategoryName := "Updated Category"
-	err = models.UpdateCategory(db, category.Name, &models.Category{Name: newCategoryName})
+	err = models.UpdateCategory(context.Background(), db, category.Name, &models.Category{Name: newCategoryName})
 	if err != nil {
 		t.Fatalf("Error updating category: %v", err)
 	}
 
 	// Retrieve all categories from database
-	categories, err := models.GetAllCategories(db)
+	categories, err := models.GetAllCategories(context.Background(), db)
 	if err != nil {
 		t.Fatalf("Error retrieving categories: %v", err)
 	}
@@ -98,6 +99,12 @@ func TestUpdateCategory(t *testing.T) {
 	if !found {
 		t.Error("Expected updated category name to exist in list of categories")
 	}
+
+	// Names of category collection routes are rejected
+	err = models.UpdateCategory(context.Background(), db, newCategoryName, &models.Category{Name: "list"})
+	assertDomainError(t, err, models.ErrValidation, models.CodeReservedCategoryName)
+	_, err = models.AddCategory(context.Background(), db, &models.Category{Name: "tree"})
+	assertDomainError(t, err, models.ErrValidation, models.CodeReservedCategoryName)
 }
 
 // TestDeleteCategory tests DeleteCategory function
@@ -116,19 +123,19 @@ func TestDeleteCategory(t *testing.T) {
 	category := &models.Category{Name: "Test Category"}
 
 	// Add category to database
-	_, err = models.AddCategory(db, category)
+	_, err = models.AddCategory(context.Background(), db, category)
 	if err != nil {
 		t.Fatalf("Error adding category: %v", err)
 	}
 
 	// Delete category from database
-	err = models.DeleteCategory(db, category.Name)
+	err = models.DeleteCategory(context.Background(), db, category.Name, models.DeletePolicy{}, models.AnyVersion)
 	if err != nil {
 		t.Fatalf("Error deleting category: %v", err)
 	}
 
 	// Retrieve all categories from database
-	categories, err := models.GetAllCategories(db)
+	categories, err := models.GetAllCategories(context.Background(), db)
 	if err != nil {
 		t.Fatalf("Error retrieving categories: %v", err)
 	}
@@ -146,17 +153,176 @@ func TestDeleteCategory(t *testing.T) {
 	}
 }
 
-// createCategoryTable creates necessary tables in database
-func createCategoryTable(db *sql.DB) {
-	createCategoriesTableQuery := `
-		CREATE TABLE IF NOT EXISTS categories (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT
-		)
-	`
+// TestMoveCategory tests MoveCategory and GetCategoryTree functions
+func TestMoveCategory(t *testing.T) {
+	// Open in-memory SQLite database
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	// Create necessary tables
+	createCategoryTable(db)
+
+	// Build hierarchy Food > Bakery > Bread
+	for _, category := range []models.Category{
+		{Name: "Food"},
+		{Name: "Bakery", Parent: "Food"},
+		{Name: "Bread", Parent: "Bakery"},
+	} {
+		if _, err := models.AddCategory(context.Background(), db, &category); err != nil {
+			t.Fatalf("Error adding category %s: %v", category.Name, err)
+		}
+	}
+
+	// Check that category with unknown parent is rejected
+	if _, err := models.AddCategory(context.Background(), db, &models.Category{Name: "Cake", Parent: "Unknown"}); err == nil {
+		t.Error("Expected error for unknown parent category, got nil")
+	}
+
+	// Check that category cannot be moved under its descendant or itself
+	if _, err := models.MoveCategory(context.Background(), db, "Food", "Bread", models.AnyVersion); err == nil {
+		t.Error("Expected error when moving category under its descendant, got nil")
+	}
+	if _, err := models.MoveCategory(context.Background(), db, "Food", "Food", models.AnyVersion); err == nil {
+		t.Error("Expected error when moving category under itself, got nil")
+	}
+
+	// Move Bread directly under Food
+	if _, err := models.MoveCategory(context.Background(), db, "Bread", "Food", models.AnyVersion); err != nil {
+		t.Fatalf("Error moving category: %v", err)
+	}
+
+	// Check tree structure
+	tree, err := models.GetCategoryTree(context.Background(), db)
+	if err != nil {
+		t.Fatalf("Error getting category tree: %v", err)
+	}
+	if len(tree) != 1 || tree[0].Name != "Food" {
+		t.Fatalf("Expected single root Food, got %+v", tree)
+	}
+	if len(tree[0].Children) != 2 || tree[0].Children[0].Name != "Bakery" || tree[0].Children[1].Name != "Bread" {
+		t.Errorf("Expected Food to contain Bakery and Bread, got %+v", tree[0].Children)
+	}
+
+	// Make Bread top-level category again
+	if _, err := models.MoveCategory(context.Background(), db, "Bread", "", models.AnyVersion); err != nil {
+		t.Fatalf("Error moving category to top level: %v", err)
+	}
+	tree, err = models.GetCategoryTree(context.Background(), db)
+	if err != nil {
+		t.Fatalf("Error getting category tree: %v", err)
+	}
+	if len(tree) != 2 {
+		t.Errorf("Expected 2 root categories, got %d", len(tree))
+	}
+}
+
+// TestDeleteCategoryKeepsChildren tests that children of deleted category move to its parent
+func TestDeleteCategoryKeepsChildren(t *testing.T) {
+	// Open in-memory SQLite database
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
 
-	_, err := db.Exec(createCategoriesTableQuery)
+	// Create necessary tables
+	createCategoryTable(db)
+
+	// Build hierarchy Food > Bakery > Bread
+	for _, category := range []models.Category{
+		{Name: "Food"},
+		{Name: "Bakery", Parent: "Food"},
+		{Name: "Bread", Parent: "Bakery"},
+	} {
+		if _, err := models.AddCategory(context.Background(), db, &category); err != nil {
+			t.Fatalf("Error adding category %s: %v", category.Name, err)
+		}
+	}
+
+	// Delete middle category
+	if err := models.DeleteCategory(context.Background(), db, "Bakery", models.DeletePolicy{}, models.AnyVersion); err != nil {
+		t.Fatalf("Error deleting category: %v", err)
+	}
+
+	// Check that Bread is now child of Food
+	tree, err := models.GetCategoryTree(context.Background(), db)
+	if err != nil {
+		t.Fatalf("Error getting category tree: %v", err)
+	}
+	if len(tree) != 1 || len(tree[0].Children) != 1 || tree[0].Children[0].Name != "Bread" {
+		t.Errorf("Expected Bread to be moved under Food, got %+v", tree)
+	}
+}
+
+// TestDeleteCategoryPolicies tests handling of products when category is deleted
+func TestDeleteCategoryPolicies(t *testing.T) {
+	db := openMigratedDB(t)
+
+	// Add products: Bread only in Bakery, Cake in Bakery and Sweets
+	for name, categories := range map[string][]models.Category{
+		"Bread": {{Name: "Bakery"}},
+		"Cake":  {{Name: "Bakery"}, {Name: "Sweets"}},
+	} {
+		if err := models.AddProduct(context.Background(), db, &models.Product{Name: name}, categories); err != nil {
+			t.Fatalf("Error adding product %s: %v", name, err)
+		}
+	}
+	if _, err := models.AddCategory(context.Background(), db, &models.Category{Name: "Food"}); err != nil {
+		t.Fatalf("Error adding category: %v", err)
+	}
+
+	// Restrict policy refuses to delete category with products
+	if err := models.DeleteCategory(context.Background(), db, "Bakery", models.DeletePolicy{}, models.AnyVersion); err == nil {
+		t.Error("Expected restrict policy to refuse deleting category with products")
+	}
+
+	// Cascade policy refuses to leave Bread without category
+	if err := models.DeleteCategory(context.Background(), db, "Bakery", models.DeletePolicy{Mode: models.DeleteCascadeLinks}, models.AnyVersion); err == nil {
+		t.Error("Expected cascade-links policy to refuse leaving product without category")
+	}
+
+	// Cascade policy removes links when every product keeps another category
+	if err := models.DeleteCategory(context.Background(), db, "Sweets", models.DeletePolicy{Mode: models.DeleteCascadeLinks}, models.AnyVersion); err != nil {
+		t.Fatalf("Error deleting category with cascade-links policy: %v", err)
+	}
+
+	// Reassign policy moves products to target category
+	if err := models.DeleteCategory(context.Background(), db, "Bakery", models.DeletePolicy{Mode: models.DeleteReassign}, models.AnyVersion); err == nil {
+		t.Error("Expected reassign-to policy without target to fail")
+	}
+	err := models.DeleteCategory(context.Background(), db, "Bakery", models.DeletePolicy{Mode: models.DeleteReassign, ReassignTo: "Food"}, models.AnyVersion)
+	if err != nil {
+		t.Fatalf("Error deleting category with reassign-to policy: %v", err)
+	}
+	products, err := models.GetProductsByCategory(context.Background(), db, "Food", false)
+	if err != nil {
+		t.Fatalf("Error getting products by category: %v", err)
+	}
+	if len(products) != 2 {
+		t.Errorf("Expected 2 products reassigned to Food, got %d", len(products))
+	}
+
+	// No links to deleted categories remain
+	var dangling int
+	err = db.QueryRow("SELECT COUNT(*) FROM product_categories WHERE category_id NOT IN (SELECT id FROM categories)").Scan(&dangling)
 	if err != nil {
-		panic(err)
+		t.Fatalf("Error counting dangling links: %v", err)
+	}
+	if dangling != 0 {
+		t.Errorf("Expected no dangling links, got %d", dangling)
+	}
+
+	// Foreign keys prevent deleting category with links directly
+	if _, err := db.Exec("DELETE FROM categories WHERE name = 'Food'"); err == nil {
+		t.Error("Expected foreign key to prevent deleting category with products")
 	}
 }
+
+// createCategoryTable creates necessary tables in database
+// Categories are linked to products, so product tables are created as well
+func createCategoryTable(db *sql.DB) {
+	createTables(db)
+}
@@ -0,0 +1,75 @@
+package models_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+)
+
+// assertDomainError checks that error is domain error of specified kind and code
+func assertDomainError(t *testing.T, err error, kind error, code string) {
+	t.Helper()
+	var domainErr *models.Error
+	if !errors.As(err, &domainErr) {
+		t.Fatalf("Expected domain error, got %v", err)
+	}
+	if !errors.Is(err, kind) {
+		t.Errorf("Expected error of kind %v, got %v", kind, domainErr.Kind)
+	}
+	if domainErr.Code != code {
+		t.Errorf("Expected code %s, got %s", code, domainErr.Code)
+	}
+}
+
+// TestDomainErrors tests that models report failures with typed domain errors
+func TestDomainErrors(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := scripts.Migrate(db); err != nil {
+		t.Fatalf("Error migrating database: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := models.AddProduct(ctx, db, &models.Product{Name: "Rex", SKU: "REX-1"}, []models.Category{{Name: "Dogs"}}); err != nil {
+		t.Fatalf("Error adding product: %v", err)
+	}
+
+	// Missing product is not found, while lookup error is still available to callers
+	_, err = models.GetProduct(ctx, db, 42)
+	assertDomainError(t, err, models.ErrNotFound, models.CodeProductNotFound)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected error wrapping sql.ErrNoRows, got %v", err)
+	}
+	assertDomainError(t, models.DeleteProduct(ctx, db, "Ghost", models.AnyVersion), models.ErrNotFound, models.CodeProductNotFound)
+
+	// Duplicate names and SKUs conflict with existing rows
+	_, err = models.AddCategory(ctx, db, &models.Category{Name: "Dogs"})
+	assertDomainError(t, err, models.ErrConflict, models.CodeCategoryExists)
+	err = models.AddProduct(ctx, db, &models.Product{Name: "Rex"}, []models.Category{{Name: "Dogs"}})
+	assertDomainError(t, err, models.ErrConflict, models.CodeProductExists)
+	err = models.AddProduct(ctx, db, &models.Product{Name: "Max", SKU: "REX-1"}, []models.Category{{Name: "Dogs"}})
+	assertDomainError(t, err, models.ErrConflict, models.CodeSKUExists)
+
+	// Invalid data fails validation
+	err = models.AddProduct(ctx, db, &models.Product{Name: "Tom", Currency: "usd"}, []models.Category{{Name: "Cats"}})
+	assertDomainError(t, err, models.ErrValidation, models.CodeInvalidCurrency)
+	err = models.DeleteCategory(ctx, db, "Dogs", models.DeletePolicy{Mode: "purge"}, models.AnyVersion)
+	assertDomainError(t, err, models.ErrValidation, models.CodeInvalidDeletePolicy)
+
+	// Wrong credentials and broken tokens are unauthorized
+	if err := models.RegisterUser(ctx, db, &models.User{Username: "alice", Password: "secret-password"}); err != nil {
+		t.Fatalf("Error registering user: %v", err)
+	}
+	_, err = models.LoginUser(ctx, db, &models.User{Username: "alice", Password: "wrong-password"})
+	assertDomainError(t, err, models.ErrUnauthorized, models.CodeInvalidCredentials)
+	_, err = models.ParseToken("not-a-token")
+	assertDomainError(t, err, models.ErrUnauthorized, models.CodeInvalidToken)
+}
@@ -0,0 +1,198 @@
+package models_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/database"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openMigratedDB opens in-memory database with enforced foreign keys and schema created by migrations
+func openMigratedDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	// In-memory database exists per connection, so keep single connection
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := scripts.Migrate(db); err != nil {
+		t.Fatalf("Error migrating database: %v", err)
+	}
+	return db
+}
+
+func TestAddProductRollback(t *testing.T) {
+	db := openMigratedDB(t)
+
+	// Category with missing parent cannot be added after product and first category are inserted
+	product := &models.Product{Name: "Bread"}
+	categories := []models.Category{{Name: "Food"}, {Name: "Bakery", Parent: "Missing"}}
+	err := models.WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		return models.AddProduct(context.Background(), tx, product, categories)
+	})
+	if err == nil {
+		t.Fatal("Expected error adding product with category of missing parent, got nil")
+	}
+
+	// Check that neither product nor category were left behind
+	if _, err := models.GetProductID(context.Background(), db, "Bread"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected product to be rolled back, got %v", err)
+	}
+	if _, err := models.GetCategoryID(context.Background(), db, "Food"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected category to be rolled back, got %v", err)
+	}
+}
+
+func TestUpdateProductRollback(t *testing.T) {
+	db := openMigratedDB(t)
+
+	// Add product
+	categories := []models.Category{{Name: "Food"}}
+	err := models.WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		return models.AddProduct(context.Background(), tx, &models.Product{Name: "Bread", Price: 100}, categories)
+	})
+	if err != nil {
+		t.Fatalf("Error adding product: %v", err)
+	}
+
+	// Update fails on category with missing parent after price has been changed
+	err = models.WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		product := &models.Product{Name: "Bread", Price: 500}
+		return models.UpdateProduct(context.Background(), tx, product, []models.Category{{Name: "Bakery", Parent: "Missing"}})
+	})
+	if err == nil {
+		t.Fatal("Expected error updating product with category of missing parent, got nil")
+	}
+
+	// Check that price and categories are unchanged
+	productID, err := models.GetProductID(context.Background(), db, "Bread")
+	if err != nil {
+		t.Fatalf("Error getting product ID: %v", err)
+	}
+	details, err := models.GetProduct(context.Background(), db, productID)
+	if err != nil {
+		t.Fatalf("Error getting product: %v", err)
+	}
+	if details.Price != 100 {
+		t.Errorf("Expected price to be rolled back to 100, got %d", details.Price)
+	}
+	if len(details.Categories) != 1 || details.Categories[0] != "Food" {
+		t.Errorf("Expected categories to be rolled back to [Food], got %v", details.Categories)
+	}
+}
+
+func TestWithTxPanic(t *testing.T) {
+	db := openMigratedDB(t)
+
+	// Panic inside transaction is propagated after rollback
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic to be propagated")
+			}
+		}()
+		models.WithTx(context.Background(), db, func(tx *sql.Tx) error {
+			if _, err := models.AddCategory(context.Background(), tx, &models.Category{Name: "Food"}); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+
+	// Check that category was rolled back
+	if _, err := models.GetCategoryID(context.Background(), db, "Food"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected category to be rolled back, got %v", err)
+	}
+}
+
+func TestConcurrentUpdateProductOnFileDatabase(t *testing.T) {
+	// Open file database with production connection settings and pool
+	if err := database.Init(filepath.Join(t.TempDir(), "catalog.db")); err != nil {
+		t.Fatalf("Error initializing database: %v", err)
+	}
+	defer database.Close()
+	db := database.GetDB()
+	if err := scripts.Migrate(db); err != nil {
+		t.Fatalf("Error migrating database: %v", err)
+	}
+
+	const updates = 30
+	ctx := context.Background()
+	for i := 0; i < updates; i++ {
+		product := &models.Product{Name: fmt.Sprintf("Product %d", i)}
+		if err := models.AddProduct(ctx, db, product, []models.Category{{Name: "Initial"}}); err != nil {
+			t.Fatalf("Error adding product: %v", err)
+		}
+	}
+
+	// Every update reads product and then writes it in its own transaction
+	var wg sync.WaitGroup
+	errs := make(chan error, updates)
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- models.WithTx(ctx, db, func(tx *sql.Tx) error {
+				product := &models.Product{Name: fmt.Sprintf("Product %d", i), Price: int64(i)}
+				return models.UpdateProduct(ctx, tx, product, []models.Category{{Name: fmt.Sprintf("Own %d", i)}})
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	// Check that no update failed on locked database
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Error updating product concurrently: %v", err)
+		}
+	}
+}
+
+func TestDeleteCategoryInOuterTransaction(t *testing.T) {
+	db := openMigratedDB(t)
+
+	ctx := context.Background()
+	if err := models.AddProduct(ctx, db, &models.Product{Name: "Bread"}, []models.Category{{Name: "Food"}, {Name: "Bakery"}}); err != nil {
+		t.Fatalf("Error adding product: %v", err)
+	}
+
+	// Deletion is rolled back together with outer transaction failing after it
+	errLater := errors.New("later step failed")
+	err := models.WithTx(ctx, db, func(tx *sql.Tx) error {
+		policy := models.DeletePolicy{Mode: models.DeleteCascadeLinks}
+		if err := models.DeleteCategory(ctx, tx, "Bakery", policy, models.AnyVersion); err != nil {
+			return err
+		}
+		return errLater
+	})
+	if !errors.Is(err, errLater) {
+		t.Fatalf("Expected error of later step, got %v", err)
+	}
+
+	// Check that category and its link to product were kept
+	if _, err := models.GetCategoryID(ctx, db, "Bakery"); err != nil {
+		t.Errorf("Expected category to be rolled back, got %v", err)
+	}
+	productID, err := models.GetProductID(ctx, db, "Bread")
+	if err != nil {
+		t.Fatalf("Error getting product ID: %v", err)
+	}
+	categories, err := models.GetCategoriesByProductID(ctx, db, productID)
+	if err != nil {
+		t.Fatalf("Error getting categories: %v", err)
+	}
+	if len(categories) != 2 {
+		t.Errorf("Expected product to keep both categories, got %v", categories)
+	}
+}
@@ -1,7 +1,9 @@
 package models_test
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/MaximInnopolis/ProductCatalog/internal/models"
@@ -20,19 +22,19 @@ func TestAddProduct(t *testing.T) {
 	product := &models.Product{Name: "Test Product"}
 	categories := []models.Category{{Name: "Test Category"}}
 
-	err = models.AddProduct(db, product, categories)
+	err = models.AddProduct(context.Background(), db, product, categories)
 	if err != nil {
 		t.Fatalf("Error adding product: %v", err)
 	}
 
 	// Retrieve product ID after adding product
-	productID, err := models.GetProductID(db, product.Name)
+	productID, err := models.GetProductID(context.Background(), db, product.Name)
 	if err != nil {
 		t.Fatalf("Error getting product ID: %v", err)
 	}
 
 	// Check if categories were associated with product
-	associatedCategories, err := models.GetCategoriesByProductID(db, productID)
+	associatedCategories, err := models.GetCategoriesByProductID(context.Background(), db, productID)
 	if err != nil {
 		t.Fatalf("Error getting categories associated with product: %v", err)
 	}
@@ -60,7 +62,7 @@ func TestUpdateProduct(t *testing.T) {
 
 	// Add test categories to database
 	for _, category := range testCategories {
-		_, err := models.AddCategory(db, &category)
+		_, err := models.AddCategory(context.Background(), db, &category)
 		if err != nil {
 			t.Fatalf("Error adding category: %v", err)
 		}
@@ -70,20 +72,20 @@ func TestUpdateProduct(t *testing.T) {
 	testProduct := &models.Product{Name: "Test Product"}
 
 	// Add test product to database
-	err = models.AddProduct(db, testProduct, testCategories)
+	err = models.AddProduct(context.Background(), db, testProduct, testCategories)
 	if err != nil {
 		t.Fatalf("Error adding product: %v", err)
 	}
 
 	// Get product ID
-	productID, err := models.GetProductID(db, testProduct.Name)
+	productID, err := models.GetProductID(context.Background(), db, testProduct.Name)
 	if err != nil {
 		t.Fatalf("Error getting product ID: %v", err)
 	}
 
 	// Update test product
 	updatedProduct := &models.Product{ID: int(productID), Name: "Test Product"}
-	err = models.UpdateProduct(db, updatedProduct, testCategories)
+	err = models.UpdateProduct(context.Background(), db, updatedProduct, testCategories)
 	if err != nil {
 		t.Fatalf("Error updating product: %v", err)
 	}
@@ -91,6 +93,225 @@ func TestUpdateProduct(t *testing.T) {
 	if updatedProduct.Name != "Test Product" {
 		t.Errorf("Expected product name to be 'Test Product', got '%s'", updatedProduct.Name)
 	}
+
+	// Product identified by ID is renamed
+	renamedProduct := &models.Product{ID: int(productID), Name: "Renamed Product"}
+	err = models.UpdateProduct(context.Background(), db, renamedProduct, testCategories)
+	if err != nil {
+		t.Fatalf("Error renaming product: %v", err)
+	}
+	if _, err := models.GetProductID(context.Background(), db, "Renamed Product"); err != nil {
+		t.Errorf("Expected product to be renamed, got error: %v", err)
+	}
+
+	// Missing product is not found by ID
+	missingProduct := &models.Product{ID: int(productID) + 1, Name: "Missing Product"}
+	err = models.UpdateProduct(context.Background(), db, missingProduct, testCategories)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected not found error for missing product, got %v", err)
+	}
+}
+
+func TestProductAttributes(t *testing.T) {
+	// Open in-memory SQLite database for testing
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	// Create tables
+	createTables(db)
+
+	// Add product with price, description and SKU
+	product := &models.Product{Name: "Bread", Price: 199, Description: "Fresh bread", SKU: "BR-001"}
+	categories := []models.Category{{Name: "Food"}}
+	err = models.AddProduct(context.Background(), db, product, categories)
+	if err != nil {
+		t.Fatalf("Error adding product: %v", err)
+	}
+
+	// Check that currency defaults to USD
+	if product.Currency != models.DefaultCurrency {
+		t.Errorf("Expected currency %s, got %s", models.DefaultCurrency, product.Currency)
+	}
+
+	// Update product attributes
+	updated := &models.Product{Name: "Bread", Price: 249, Currency: "EUR", Description: "Rye bread", SKU: "BR-002"}
+	err = models.UpdateProduct(context.Background(), db, updated, categories)
+	if err != nil {
+		t.Fatalf("Error updating product: %v", err)
+	}
+
+	// Read product back through category listing
+	products, err := models.GetProductsByCategory(context.Background(), db, "Food", false)
+	if err != nil {
+		t.Fatalf("Error getting products by category: %v", err)
+	}
+	if len(products) != 1 {
+		t.Fatalf("Expected 1 product, got %d", len(products))
+	}
+
+	got := products[0]
+	if got.Price != 249 || got.Currency != "EUR" || got.Description != "Rye bread" || got.SKU != "BR-002" {
+		t.Errorf("Unexpected product attributes: %+v", got)
+	}
+	if got.CreatedAt.IsZero() || got.UpdatedAt.Before(got.CreatedAt) {
+		t.Errorf("Unexpected product timestamps: created %v, updated %v", got.CreatedAt, got.UpdatedAt)
+	}
+
+	// Check that invalid currency is rejected
+	err = models.AddProduct(context.Background(), db, &models.Product{Name: "Cake", Currency: "euro"}, categories)
+	if err == nil {
+		t.Error("Expected error for invalid currency, got nil")
+	}
+
+	// Check that negative price is rejected
+	err = models.AddProduct(context.Background(), db, &models.Product{Name: "Cake", Price: -1}, categories)
+	if err == nil {
+		t.Error("Expected error for negative price, got nil")
+	}
+}
+
+func TestGetProductsByCategoryRecursive(t *testing.T) {
+	// Open in-memory SQLite database for testing
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	// Create tables
+	createTables(db)
+
+	// Build hierarchy Food > Bakery > Bread
+	for _, category := range []models.Category{
+		{Name: "Food"},
+		{Name: "Bakery", Parent: "Food"},
+		{Name: "Bread", Parent: "Bakery"},
+	} {
+		if _, err := models.AddCategory(context.Background(), db, &category); err != nil {
+			t.Fatalf("Error adding category %s: %v", category.Name, err)
+		}
+	}
+
+	// Add products on different levels, one of them in two levels at once
+	products := map[string][]models.Category{
+		"Apple":     {{Name: "Food"}},
+		"Croissant": {{Name: "Bakery"}},
+		"Baguette":  {{Name: "Bread"}, {Name: "Bakery"}},
+	}
+	for name, categories := range products {
+		if err := models.AddProduct(context.Background(), db, &models.Product{Name: name}, categories); err != nil {
+			t.Fatalf("Error adding product %s: %v", name, err)
+		}
+	}
+
+	// Without recursion only products directly in category are returned
+	direct, err := models.GetProductsByCategory(context.Background(), db, "Food", false)
+	if err != nil {
+		t.Fatalf("Error getting products by category: %v", err)
+	}
+	if len(direct) != 1 {
+		t.Errorf("Expected 1 product directly in Food, got %d", len(direct))
+	}
+
+	// With recursion products of all descendants are returned exactly once
+	all, err := models.GetProductsByCategory(context.Background(), db, "Food", true)
+	if err != nil {
+		t.Fatalf("Error getting products by category recursively: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("Expected 3 products in Food subtree, got %d", len(all))
+	}
+}
+
+func TestGetProducts(t *testing.T) {
+	// Open in-memory SQLite database for testing
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	// Create tables
+	createTables(db)
+
+	// Add products with different prices
+	categories := []models.Category{{Name: "Food"}}
+	for i, name := range []string{"Cheese", "Apple", "Bread"} {
+		product := &models.Product{Name: name, Price: int64(100 * (i + 1))}
+		if err := models.AddProduct(context.Background(), db, product, categories); err != nil {
+			t.Fatalf("Error adding product %s: %v", name, err)
+		}
+	}
+
+	// Get first page sorted by name
+	options := models.ProductListOptions{Limit: 2, Sort: "name"}
+	products, total, err := models.GetProducts(context.Background(), db, &options)
+	if err != nil {
+		t.Fatalf("Error getting products: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected total of 3 products, got %d", total)
+	}
+	if len(products) != 2 || products[0].Name != "Apple" || products[1].Name != "Bread" {
+		t.Errorf("Unexpected first page: %+v", products)
+	}
+
+	// Get second page sorted by price in descending order
+	options = models.ProductListOptions{Limit: 2, Offset: 2, Sort: "price", Desc: true}
+	products, _, err = models.GetProducts(context.Background(), db, &options)
+	if err != nil {
+		t.Fatalf("Error getting products: %v", err)
+	}
+	if len(products) != 1 || products[0].Name != "Cheese" {
+		t.Errorf("Unexpected second page: %+v", products)
+	}
+
+	// Check that default limit is applied and unknown sort field is rejected
+	options = models.ProductListOptions{}
+	if _, _, err = models.GetProducts(context.Background(), db, &options); err != nil || options.Limit != models.DefaultProductsLimit {
+		t.Errorf("Expected default limit %d, got %d (error %v)", models.DefaultProductsLimit, options.Limit, err)
+	}
+	options = models.ProductListOptions{Sort: "password"}
+	if _, _, err = models.GetProducts(context.Background(), db, &options); err == nil {
+		t.Error("Expected error for unsupported sort field, got nil")
+	}
+}
+
+func TestGetProduct(t *testing.T) {
+	// Open in-memory SQLite database for testing
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	// Create tables
+	createTables(db)
+
+	// Add product with two categories
+	product := &models.Product{Name: "Bread", Price: 150}
+	categories := []models.Category{{Name: "Food"}, {Name: "Bakery"}}
+	if err := models.AddProduct(context.Background(), db, product, categories); err != nil {
+		t.Fatalf("Error adding product: %v", err)
+	}
+
+	// Get product by ID
+	details, err := models.GetProduct(context.Background(), db, int64(product.ID))
+	if err != nil {
+		t.Fatalf("Error getting product: %v", err)
+	}
+	if details.Name != "Bread" || details.Price != 150 || len(details.Categories) != 2 {
+		t.Errorf("Unexpected product details: %+v", details)
+	}
+
+	// Check that missing product is reported with sql.ErrNoRows
+	_, err = models.GetProduct(context.Background(), db, 999)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for missing product, got %v", err)
+	}
 }
 
 func TestDeleteProduct(t *testing.T) {
@@ -109,19 +330,19 @@ func TestDeleteProduct(t *testing.T) {
 	categories := []models.Category{{Name: "Test Category"}}
 
 	// Add product
-	err = models.AddProduct(db, product, categories)
+	err = models.AddProduct(context.Background(), db, product, categories)
 	if err != nil {
 		t.Fatalf("Error adding product: %v", err)
 	}
 
 	// Delete product
-	err = models.DeleteProduct(db, product.Name)
+	err = models.DeleteProduct(context.Background(), db, product.Name, models.AnyVersion)
 	if err != nil {
 		t.Fatalf("Error deleting product: %v", err)
 	}
 
 	// Check if product was deleted successfully
-	_, err = models.GetProductID(db, product.Name)
+	_, err = models.GetProductID(context.Background(), db, product.Name)
 	if err == nil {
 		t.Error("Expected product to be deleted, but it still exists in database")
 	}
@@ -170,13 +391,13 @@ func TestGetCategoryID(t *testing.T) {
 
 	// Insert category into database
 	categoryName := "Test Category"
-	categoryID, err := models.AddCategory(db, &models.Category{Name: categoryName})
+	categoryID, err := models.AddCategory(context.Background(), db, &models.Category{Name: categoryName})
 	if err != nil {
 		t.Fatalf("Error adding category: %v", err)
 	}
 
 	// Retrieve ID of inserted category
-	retrievedCategoryID, err := models.GetCategoryID(db, categoryName)
+	retrievedCategoryID, err := models.GetCategoryID(context.Background(), db, categoryName)
 	if err != nil {
 		t.Fatalf("Error retrieving category ID: %v", err)
 	}
@@ -190,14 +411,26 @@ func createTables(db *sql.DB) {
 	createCategoriesTableQuery := `
 		CREATE TABLE IF NOT EXISTS categories (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT
+			name TEXT,
+			parent_id INTEGER REFERENCES categories(id),
+			version INTEGER NOT NULL DEFAULT 1
 		)
 	`
 
 	createProductsTableQuery := `
 		CREATE TABLE IF NOT EXISTS products (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT
+			name TEXT,
+			price INTEGER NOT NULL DEFAULT 0,
+			currency TEXT NOT NULL DEFAULT 'USD',
+			description TEXT NOT NULL DEFAULT '',
+			sku TEXT UNIQUE,
+			created_at DATETIME,
+			updated_at DATETIME,
+			source TEXT,
+			external_id TEXT,
+			discontinued_at DATETIME,
+			version INTEGER NOT NULL DEFAULT 1
 		)
 	`
 
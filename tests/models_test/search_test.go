@@ -0,0 +1,129 @@
+package models_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openSearchDB opens in-memory database with full schema and skips test if FTS5 is not compiled in
+func openSearchDB(t *testing.T) *sql.DB {
+	db := openMigratedDB(t)
+
+	_, err := models.SearchProducts(context.Background(), db, models.SearchOptions{Query: "probe"})
+	if errors.Is(err, models.ErrSearchUnavailable) {
+		t.Skip("SQLite built without FTS5, run tests with -tags sqlite_fts5")
+	}
+	return db
+}
+
+func TestSearchProducts(t *testing.T) {
+	db := openSearchDB(t)
+
+	// Add products to search among
+	products := []struct {
+		product  models.Product
+		category string
+	}{
+		{models.Product{Name: "Rye bread", Description: "Dark bread baked with rye flour"}, "Bakery"},
+		{models.Product{Name: "Baguette", Description: "French bread"}, "Bakery"},
+		{models.Product{Name: "Breadboard", Description: "Wooden board for cutting"}, "Kitchen"},
+		{models.Product{Name: "Milk", Description: "Fresh milk"}, "Dairy"},
+	}
+	for _, p := range products {
+		product := p.product
+		if err := models.AddProduct(context.Background(), db, &product, []models.Category{{Name: p.category}}); err != nil {
+			t.Fatalf("Error adding product %s: %v", product.Name, err)
+		}
+	}
+
+	// Prefix query matches names and descriptions, name matches rank first
+	results, err := models.SearchProducts(context.Background(), db, models.SearchOptions{Query: "bread"})
+	if err != nil {
+		t.Fatalf("Error searching products: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	if results[0].Name == "Baguette" {
+		t.Errorf("Expected product with match in name to rank first, got %s", results[0].Name)
+	}
+	if !strings.Contains(results[0].Highlight, "<mark>") {
+		t.Errorf("Expected highlighted name, got %q", results[0].Highlight)
+	}
+
+	// Category filter restricts results
+	results, err = models.SearchProducts(context.Background(), db, models.SearchOptions{Query: "bread", Category: "Kitchen"})
+	if err != nil {
+		t.Fatalf("Error searching products: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "Breadboard" {
+		t.Errorf("Expected only Breadboard in Kitchen, got %+v", results)
+	}
+
+	// Index follows updates and deletions
+	if err := models.UpdateProduct(context.Background(), db, &models.Product{Name: "Milk", Description: "Goes well with bread"},
+		[]models.Category{{Name: "Dairy"}}); err != nil {
+		t.Fatalf("Error updating product: %v", err)
+	}
+	if err := models.DeleteProduct(context.Background(), db, "Baguette", models.AnyVersion); err != nil {
+		t.Fatalf("Error deleting product: %v", err)
+	}
+	results, err = models.SearchProducts(context.Background(), db, models.SearchOptions{Query: "bread"})
+	if err != nil {
+		t.Fatalf("Error searching products: %v", err)
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected 3 results after update and delete, got %d", len(results))
+	}
+	for _, result := range results {
+		if result.Name == "Baguette" {
+			t.Error("Expected deleted product to be removed from search index")
+		}
+	}
+
+	// Operators in user input are treated as plain words
+	if _, err := models.SearchProducts(context.Background(), db, models.SearchOptions{Query: `bread" OR NEAR(`}); err != nil {
+		t.Errorf("Expected special characters to be escaped, got %v", err)
+	}
+}
+
+// TestSearchResultsAreSafe tests that highlighted text is escaped and discontinued products are not found
+func TestSearchResultsAreSafe(t *testing.T) {
+	db := openSearchDB(t)
+
+	products := []models.Product{
+		{Name: `<img src=x onerror=alert(1)> bread`, Description: `<script>alert("bread")</script>`},
+		{Name: "Old bread", Description: "No longer sold", Source: "bakery", ExternalID: "old"},
+	}
+	for i := range products {
+		if err := models.AddProduct(context.Background(), db, &products[i], []models.Category{{Name: "Bakery"}}); err != nil {
+			t.Fatalf("Error adding product %s: %v", products[i].Name, err)
+		}
+	}
+	if _, err := models.DiscontinueMissingProducts(context.Background(), db, "bakery", nil); err != nil {
+		t.Fatalf("Error discontinuing products: %v", err)
+	}
+
+	results, err := models.SearchProducts(context.Background(), db, models.SearchOptions{Query: "bread"})
+	if err != nil {
+		t.Fatalf("Error searching products: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected only product on sale to be found, got %d results", len(results))
+	}
+
+	expectedHighlight := "&lt;img src=x onerror=alert(1)&gt; <mark>bread</mark>"
+	if results[0].Highlight != expectedHighlight {
+		t.Errorf("Expected highlight %q, got %q", expectedHighlight, results[0].Highlight)
+	}
+	expectedSnippet := "&lt;script&gt;alert(&#34;<mark>bread</mark>&#34;)&lt;/script&gt;"
+	if results[0].Snippet != expectedSnippet {
+		t.Errorf("Expected snippet %q, got %q", expectedSnippet, results[0].Snippet)
+	}
+}
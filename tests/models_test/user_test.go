@@ -1,13 +1,15 @@
 package models_test
 
 import (
+	"context"
 	"database/sql"
 	"github.com/MaximInnopolis/ProductCatalog/internal/models"
 	_ "github.com/mattn/go-sqlite3"
 	"testing"
+	"time"
 )
 
-func TestIsTokenValid(t *testing.T) {
+func TestRegisterUser(t *testing.T) {
 	// Create temporary database
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
@@ -16,66 +18,49 @@ func TestIsTokenValid(t *testing.T) {
 	defer db.Close()
 
 	// Create users table
-	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT, password TEXT)")
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT, password TEXT, role TEXT NOT NULL DEFAULT 'viewer')")
 	if err != nil {
 		t.Fatalf("Error creating users table: %v", err)
 	}
 
 	// Create user
 	user := &models.User{Username: "testuser", Password: "testpassword"}
-	if err := models.RegisterUser(db, user); err != nil {
-		t.Fatalf("Error registering user: %v", err)
-	}
 
-	// Generate JWT token
-	token, err := models.LoginUser(db, user)
+	// Register user
+	err = models.RegisterUser(context.Background(), db, user)
 	if err != nil {
-		t.Fatalf("Error generating JWT token: %v", err)
+		t.Fatalf("Error registering user: %v", err)
 	}
 
-	// Check token validity
-	valid, err := models.IsTokenValid(db, token)
+	// Check if registered user exists
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", user.Username).Scan(&count)
 	if err != nil {
-		t.Fatalf("Error checking token validity: %v", err)
+		t.Fatalf("Error executing query: %v", err)
 	}
 
-	if !valid {
-		t.Error("Expected  valid token, but got invalid one")
+	if count != 1 {
+		t.Error("Expected user to be registered in database, but no records found")
 	}
 }
 
-func TestRegisterUser(t *testing.T) {
-	// Create temporary database
-	db, err := sql.Open("sqlite3", ":memory:")
-	if err != nil {
-		t.Fatalf("Error opening database: %v", err)
-	}
-	defer db.Close()
-
-	// Create users table
-	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT, password TEXT)")
-	if err != nil {
-		t.Fatalf("Error creating users table: %v", err)
-	}
-
-	// Create user
-	user := &models.User{Username: "testuser", Password: "testpassword"}
+func TestRegisterTakenUsername(t *testing.T) {
+	db := openMigratedDB(t)
 
-	// Register user
-	err = models.RegisterUser(db, user)
-	if err != nil {
+	// Second user with same username is rejected by unique index
+	if err := models.RegisterUser(context.Background(), db, &models.User{Username: "testuser", Password: "testpassword"}); err != nil {
 		t.Fatalf("Error registering user: %v", err)
 	}
+	err := models.RegisterUser(context.Background(), db, &models.User{Username: "testuser", Password: "otherpassword"})
+	assertDomainError(t, err, models.ErrConflict, models.CodeUsernameTaken)
 
-	// Check if registered user exists
+	// Check that only first user was stored
 	var count int
-	err = db.QueryRow("SELECT COUNT(*) FROM users WHERE username = ?", user.Username).Scan(&count)
-	if err != nil {
+	if err := db.QueryRow("SELECT COUNT(*) FROM users WHERE username = 'testuser'").Scan(&count); err != nil {
 		t.Fatalf("Error executing query: %v", err)
 	}
-
 	if count != 1 {
-		t.Error("Expected user to be registered in database, but no records found")
+		t.Errorf("Expected single user, got %d", count)
 	}
 }
 
@@ -88,7 +73,7 @@ func TestLoginUser(t *testing.T) {
 	defer db.Close()
 
 	// Create users table
-	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT, password TEXT)")
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT, password TEXT, role TEXT NOT NULL DEFAULT 'viewer')")
 	if err != nil {
 		t.Fatalf("Error creating users table: %v", err)
 	}
@@ -97,19 +82,19 @@ func TestLoginUser(t *testing.T) {
 	user := &models.User{Username: "testuser", Password: "testpassword"}
 
 	// Register user
-	err = models.RegisterUser(db, user)
+	err = models.RegisterUser(context.Background(), db, user)
 	if err != nil {
 		t.Fatalf("Error registering user: %v", err)
 	}
 
 	// Attempt to login user
-	_, err = models.LoginUser(db, user)
+	_, err = models.LoginUser(context.Background(), db, user)
 	if err != nil {
 		t.Fatalf("Error attempting to login user: %v", err)
 	}
 }
 
-func TestCheckTokenValid(t *testing.T) {
+func TestParseTokenValid(t *testing.T) {
 	// Generate valid token
 	user := &models.User{ID: 123, Username: "testuser"}
 	tokenString, err := models.GenerateJWT(user)
@@ -118,12 +103,12 @@ func TestCheckTokenValid(t *testing.T) {
 	}
 
 	// Check validity of token
-	valid, err := models.CheckToken(tokenString)
+	claims, err := models.ParseToken(tokenString)
 	if err != nil {
-		t.Fatalf("Error checking token: %v", err)
+		t.Fatalf("Error parsing token: %v", err)
 	}
-	if !valid {
-		t.Error("Expected token to be valid, got invalid")
+	if claims.Username != "testuser" {
+		t.Errorf("Expected claims of testuser, got %+v", claims)
 	}
 }
 
@@ -142,3 +127,137 @@ func TestGenerateJWT(t *testing.T) {
 		t.Error("Expected non-empty token string, got empty")
 	}
 }
+
+func TestUserRoles(t *testing.T) {
+	// Create temporary database
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+
+	// Create users table
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, username TEXT, password TEXT, role TEXT NOT NULL DEFAULT 'viewer')")
+	if err != nil {
+		t.Fatalf("Error creating users table: %v", err)
+	}
+
+	// Register user trying to grant itself admin role
+	user := &models.User{Username: "testuser", Password: "testpassword", Role: models.RoleAdmin}
+	if err := models.RegisterUser(context.Background(), db, user); err != nil {
+		t.Fatalf("Error registering user: %v", err)
+	}
+
+	// Check that registered user got viewer role embedded in token
+	token, err := models.LoginUser(context.Background(), db, &models.User{Username: "testuser", Password: "testpassword"})
+	if err != nil {
+		t.Fatalf("Error logging in user: %v", err)
+	}
+	claims, err := models.ParseToken(token)
+	if err != nil {
+		t.Fatalf("Error parsing token: %v", err)
+	}
+	if claims.Role != models.RoleViewer || claims.Username != "testuser" {
+		t.Errorf("Expected viewer claims for testuser, got %+v", claims)
+	}
+
+	// Promote user to editor and check new token
+	if err := models.SetUserRole(context.Background(), db, "testuser", models.RoleEditor); err != nil {
+		t.Fatalf("Error setting user role: %v", err)
+	}
+	token, err = models.LoginUser(context.Background(), db, &models.User{Username: "testuser", Password: "testpassword"})
+	if err != nil {
+		t.Fatalf("Error logging in user: %v", err)
+	}
+	claims, err = models.ParseToken(token)
+	if err != nil {
+		t.Fatalf("Error parsing token: %v", err)
+	}
+	if claims.Role != models.RoleEditor {
+		t.Errorf("Expected editor role, got %s", claims.Role)
+	}
+
+	// Check that unknown roles and users are rejected
+	if err := models.SetUserRole(context.Background(), db, "testuser", "superuser"); err == nil {
+		t.Error("Expected error for unknown role, got nil")
+	}
+	if err := models.SetUserRole(context.Background(), db, "nobody", models.RoleAdmin); err == nil {
+		t.Error("Expected error for unknown user, got nil")
+	}
+
+	// Bootstrap admin for new user
+	if err := models.BootstrapAdmin(context.Background(), db, "admin", "adminpassword"); err != nil {
+		t.Fatalf("Error bootstrapping admin: %v", err)
+	}
+	var role string
+	if err := db.QueryRow("SELECT role FROM users WHERE username = 'admin'").Scan(&role); err != nil {
+		t.Fatalf("Error getting admin role: %v", err)
+	}
+	if role != models.RoleAdmin {
+		t.Errorf("Expected admin role, got %s", role)
+	}
+
+	// Demotion of bootstrapped admin is kept when it is bootstrapped again
+	if err := models.SetUserRole(context.Background(), db, "admin", models.RoleViewer); err != nil {
+		t.Fatalf("Error setting user role: %v", err)
+	}
+	if err := models.BootstrapAdmin(context.Background(), db, "admin", "adminpassword"); err != nil {
+		t.Fatalf("Error bootstrapping admin: %v", err)
+	}
+	if err := db.QueryRow("SELECT role FROM users WHERE username = 'admin'").Scan(&role); err != nil {
+		t.Fatalf("Error getting admin role: %v", err)
+	}
+	if role != models.RoleViewer {
+		t.Errorf("Expected demoted admin to keep viewer role, got %s", role)
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	testCases := []struct {
+		role, required string
+		expected       bool
+	}{
+		{models.RoleAdmin, models.RoleEditor, true},
+		{models.RoleEditor, models.RoleEditor, true},
+		{models.RoleViewer, models.RoleEditor, false},
+		{models.RoleEditor, models.RoleAdmin, false},
+		{"", models.RoleViewer, false},
+	}
+
+	for _, tc := range testCases {
+		if got := models.HasRole(tc.role, tc.required); got != tc.expected {
+			t.Errorf("HasRole(%q, %q) = %v, expected %v", tc.role, tc.required, got, tc.expected)
+		}
+	}
+}
+
+func TestConfigureTokens(t *testing.T) {
+	defer models.ConfigureTokens(models.TokenSettings{})
+
+	user := &models.User{ID: 1, Username: "testuser", Role: models.RoleViewer}
+	models.ConfigureTokens(models.TokenSettings{SecretKey: "first-secret-key-0123456789abcdef", TTL: time.Minute})
+	token, err := models.GenerateJWT(user)
+	if err != nil {
+		t.Fatalf("Error generating token: %v", err)
+	}
+	claims, err := models.ParseToken(token)
+	if err != nil || claims.Username != "testuser" {
+		t.Fatalf("Expected valid token of testuser, got %+v, %v", claims, err)
+	}
+
+	// Token signed with previous secret key is rejected
+	models.ConfigureTokens(models.TokenSettings{SecretKey: "second-secret-key-0123456789abcdef"})
+	if _, err := models.ParseToken(token); err == nil {
+		t.Error("Expected token signed with previous secret key to be rejected")
+	}
+
+	// Non-positive lifetime falls back to default one
+	models.ConfigureTokens(models.TokenSettings{SecretKey: "second-secret-key-0123456789abcdef", TTL: -time.Minute})
+	token, err = models.GenerateJWT(user)
+	if err != nil {
+		t.Fatalf("Error generating token: %v", err)
+	}
+	if _, err := models.ParseToken(token); err != nil {
+		t.Errorf("Expected token with default lifetime to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package models_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+)
+
+// getProductVersion returns stored version of product with specified ID
+func getProductVersion(t *testing.T, db *sql.DB, productID int) int64 {
+	t.Helper()
+	product, err := models.GetProduct(context.Background(), db, int64(productID))
+	if err != nil {
+		t.Fatalf("Error getting product: %v", err)
+	}
+	return product.Version
+}
+
+// getCategoryVersion returns stored version of category with specified name
+func getCategoryVersion(t *testing.T, db *sql.DB, name string) int64 {
+	t.Helper()
+	category, err := models.GetCategory(context.Background(), db, name)
+	if err != nil {
+		t.Fatalf("Error getting category: %v", err)
+	}
+	return category.Version
+}
+
+// TestProductVersions tests that product versions grow with changes and that stale versions are rejected
+func TestProductVersions(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := scripts.Migrate(db); err != nil {
+		t.Fatalf("Error migrating database: %v", err)
+	}
+	ctx := context.Background()
+
+	product := &models.Product{Name: "Rex"}
+	if err := models.AddProduct(ctx, db, product, []models.Category{{Name: "Dogs"}}); err != nil {
+		t.Fatalf("Error adding product: %v", err)
+	}
+	if product.Version != 1 || getProductVersion(t, db, product.ID) != 1 {
+		t.Fatalf("Expected new product to have version 1, got %d", product.Version)
+	}
+
+	// Update with current version succeeds and reports new version
+	update := &models.Product{Name: "Rex", Price: 100, Version: 1}
+	if err := models.UpdateProduct(ctx, db, update, []models.Category{{Name: "Dogs"}}); err != nil {
+		t.Fatalf("Error updating product: %v", err)
+	}
+	if update.Version != 2 || getProductVersion(t, db, product.ID) != 2 {
+		t.Errorf("Expected version 2 after update, got %d", update.Version)
+	}
+
+	// Update and deletion with outdated version fail and change nothing
+	stale := &models.Product{Name: "Rex", Price: 200, Version: 1}
+	err = models.UpdateProduct(ctx, db, stale, []models.Category{{Name: "Dogs"}})
+	assertDomainError(t, err, models.ErrPreconditionFailed, models.CodeVersionMismatch)
+	err = models.DeleteProduct(ctx, db, "Rex", 1)
+	assertDomainError(t, err, models.ErrPreconditionFailed, models.CodeVersionMismatch)
+	details, err := models.GetProduct(ctx, db, int64(product.ID))
+	if err != nil {
+		t.Fatalf("Error getting product: %v", err)
+	}
+	if details.Price != 100 || details.Version != 2 {
+		t.Errorf("Expected product unchanged by stale requests, got price %d and version %d", details.Price, details.Version)
+	}
+
+	// Renaming category changes representation and modification time of its products
+	before, err := models.GetProduct(ctx, db, int64(product.ID))
+	if err != nil {
+		t.Fatalf("Error getting product: %v", err)
+	}
+	if err := models.UpdateCategory(ctx, db, "Dogs", &models.Category{Name: "Hounds"}); err != nil {
+		t.Fatalf("Error updating category: %v", err)
+	}
+	after, err := models.GetProduct(ctx, db, int64(product.ID))
+	if err != nil {
+		t.Fatalf("Error getting product: %v", err)
+	}
+	if after.Version != 3 {
+		t.Errorf("Expected version 3 after category rename, got %d", after.Version)
+	}
+	if !after.UpdatedAt.After(before.UpdatedAt) {
+		t.Errorf("Expected modification time to move after category rename, got %v and %v", before.UpdatedAt, after.UpdatedAt)
+	}
+
+	// Deletion with current version succeeds
+	if err := models.DeleteProduct(ctx, db, "Rex", 3); err != nil {
+		t.Fatalf("Error deleting product: %v", err)
+	}
+}
+
+// TestCategoryVersions tests that category versions grow with changes and that stale versions are rejected
+func TestCategoryVersions(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error opening database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if err := scripts.Migrate(db); err != nil {
+		t.Fatalf("Error migrating database: %v", err)
+	}
+	ctx := context.Background()
+
+	for _, category := range []*models.Category{{Name: "Food"}, {Name: "Bakery", Parent: "Food"}, {Name: "Dairy"}} {
+		if _, err := models.AddCategory(ctx, db, category); err != nil {
+			t.Fatalf("Error adding category: %v", err)
+		}
+	}
+	product := &models.Product{Name: "Bread"}
+	if err := models.AddProduct(ctx, db, product, []models.Category{{Name: "Bakery"}, {Name: "Dairy"}}); err != nil {
+		t.Fatalf("Error adding product: %v", err)
+	}
+
+	// Stale rename, move and deletion fail
+	category := &models.Category{Name: "Groceries", Version: 2}
+	assertDomainError(t, models.UpdateCategory(ctx, db, "Food", category), models.ErrPreconditionFailed, models.CodeVersionMismatch)
+	_, err = models.MoveCategory(ctx, db, "Bakery", "", 2)
+	assertDomainError(t, err, models.ErrPreconditionFailed, models.CodeVersionMismatch)
+	assertDomainError(t, models.DeleteCategory(ctx, db, "Food", models.DeletePolicy{}, 2), models.ErrPreconditionFailed, models.CodeVersionMismatch)
+
+	// Renaming parent changes its subcategories, as they show name of parent
+	category.Version = 1
+	if err := models.UpdateCategory(ctx, db, "Food", category); err != nil {
+		t.Fatalf("Error updating category: %v", err)
+	}
+	if category.Version != 2 || getCategoryVersion(t, db, "Groceries") != 2 {
+		t.Errorf("Expected version 2 after rename, got %d", category.Version)
+	}
+	if version := getCategoryVersion(t, db, "Bakery"); version != 2 {
+		t.Errorf("Expected subcategory version 2 after rename of parent, got %d", version)
+	}
+
+	// Move with current version succeeds
+	moved, err := models.MoveCategory(ctx, db, "Bakery", "", 2)
+	if err != nil {
+		t.Fatalf("Error moving category: %v", err)
+	}
+	if version := getCategoryVersion(t, db, "Bakery"); version != 3 || moved != 3 {
+		t.Errorf("Expected version 3 after move, got %d, reported %d", version, moved)
+	}
+
+	// Deleting category changes products losing link to it
+	productVersion := getProductVersion(t, db, product.ID)
+	if err := models.DeleteCategory(ctx, db, "Bakery", models.DeletePolicy{Mode: models.DeleteCascadeLinks}, 3); err != nil {
+		t.Fatalf("Error deleting category: %v", err)
+	}
+	if version := getProductVersion(t, db, product.ID); version != productVersion+1 {
+		t.Errorf("Expected product version %d after deletion of its category, got %d", productVersion+1, version)
+	}
+}
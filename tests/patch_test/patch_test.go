@@ -0,0 +1,173 @@
+package patch_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/patch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// apply decodes patch of specified media type and applies it to document, both given as JSON
+// Returns changed document as JSON
+func apply(t *testing.T, mediaType, document, p string) (string, error) {
+	t.Helper()
+	decoded, err := patch.Decode(mediaType, []byte(p))
+	if err != nil {
+		return "", err
+	}
+	var value interface{}
+	require.NoError(t, json.Unmarshal([]byte(document), &value))
+	value, err = patch.Value(value)
+	require.NoError(t, err)
+
+	value, err = decoded.Apply(value)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	return string(data), nil
+}
+
+// TestMergePatch tests examples of RFC 7396
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		document, patch, result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, test := range tests {
+		result, err := apply(t, patch.MergePatchType, test.document, test.patch)
+		require.NoError(t, err)
+		assert.JSONEq(t, test.result, result, "merge %s into %s", test.patch, test.document)
+	}
+}
+
+// TestJSONPatch tests operations of JSON Patch with examples of RFC 6902
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name, document, patch, result string
+	}{
+		{"add member", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"add element", `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"append element", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc"]}]`, `{"foo":["bar",["abc"]]}`},
+		{"add null", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":null}]`, `{"foo":"bar","baz":null}`},
+		{"replace document", `{"foo":"bar"}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{"remove member", `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"remove element", `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"replace", `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"move member", `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`,
+			`[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"move element", `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`,
+			`{"foo":["all","cows","eat","grass"]}`},
+		{"copy", `{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"add","path":"/baz/bar","value":2}]`,
+			`{"foo":{"bar":1},"baz":{"bar":2}}`},
+		{"test", `{"baz":"qux","foo":["a",2,"c"]}`,
+			`[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{"escaped pointer", `{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+		{"unknown members", `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"baz":"qux","foo":"bar"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := apply(t, patch.JSONPatchType, test.document, test.patch)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.result, result)
+		})
+	}
+}
+
+// TestJSONPatchConflicts tests operations that cannot be applied to document
+func TestJSONPatchConflicts(t *testing.T) {
+	tests := []struct {
+		name, document, patch string
+	}{
+		{"add to missing parent", `{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{"remove missing", `{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`},
+		{"replace missing", `{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`},
+		{"member of scalar", `{"foo":1}`, `[{"op":"add","path":"/foo/bar","value":2}]`},
+		{"failed test", `{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`},
+		{"failed test of number", `{"baz":"10"}`, `[{"op":"test","path":"/baz","value":10}]`},
+		{"later operation fails", `{"baz":"qux"}`, `[{"op":"remove","path":"/baz"},{"op":"test","path":"/baz","value":"qux"}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := apply(t, patch.JSONPatchType, test.document, test.patch)
+			require.ErrorIs(t, err, models.ErrConflict)
+			var domainErr *models.Error
+			require.ErrorAs(t, err, &domainErr)
+			assert.Equal(t, models.CodePatchConflict, domainErr.Code)
+		})
+	}
+}
+
+// TestJSONPatchInvalidOperations tests operations that are invalid for document, which are not conflicts
+func TestJSONPatchInvalidOperations(t *testing.T) {
+	tests := []struct {
+		name, document, patch string
+	}{
+		{"add out of range", `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":"qux"}]`},
+		{"remove out of range", `{"foo":["bar"]}`, `[{"op":"remove","path":"/foo/1"}]`},
+		{"index with leading zero", `{"foo":["bar","baz"]}`, `[{"op":"remove","path":"/foo/01"}]`},
+		{"non-numeric index", `{"foo":["bar"]}`, `[{"op":"replace","path":"/foo/first","value":"baz"}]`},
+		{"negative index", `{"foo":["bar"]}`, `[{"op":"test","path":"/foo/-1","value":"bar"}]`},
+		{"move into child", `{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/baz"}]`},
+		{"remove whole document", `{"foo":"bar"}`, `[{"op":"remove","path":""}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := apply(t, patch.JSONPatchType, test.document, test.patch)
+			require.ErrorIs(t, err, models.ErrValidation)
+			var domainErr *models.Error
+			require.ErrorAs(t, err, &domainErr)
+			assert.Equal(t, models.CodeInvalidPatch, domainErr.Code)
+		})
+	}
+
+	// Operations of patch built without Decode are checked as well
+	operations := patch.JSONPatch{{Op: "add", Path: "foo"}}
+	_, err := operations.Apply(map[string]interface{}{})
+	require.ErrorIs(t, err, models.ErrValidation)
+}
+
+// TestDecodeInvalidPatch tests that invalid patches are rejected before they are applied
+func TestDecodeInvalidPatch(t *testing.T) {
+	tests := []struct {
+		name, mediaType, patch string
+	}{
+		{"merge patch is not JSON", patch.MergePatchType, `{"a":`},
+		{"JSON patch is object", patch.JSONPatchType, `{"op":"remove","path":"/a"}`},
+		{"unknown operation", patch.JSONPatchType, `[{"op":"drop","path":"/a"}]`},
+		{"missing value", patch.JSONPatchType, `[{"op":"add","path":"/a"}]`},
+		{"pointer without slash", patch.JSONPatchType, `[{"op":"remove","path":"a"}]`},
+		{"invalid escape", patch.JSONPatchType, `[{"op":"remove","path":"/a~2"}]`},
+		{"invalid from", patch.JSONPatchType, `[{"op":"copy","from":"a","path":"/b"}]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := patch.Decode(test.mediaType, []byte(test.patch))
+			require.ErrorIs(t, err, models.ErrMalformed)
+			var domainErr *models.Error
+			require.ErrorAs(t, err, &domainErr)
+			assert.Equal(t, models.CodeInvalidPatch, domainErr.Code)
+		})
+	}
+}
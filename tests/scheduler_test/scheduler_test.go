@@ -0,0 +1,112 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	// Friday 2024-05-03 10:17
+	now := time.Date(2024, 5, 3, 10, 17, 30, 0, time.UTC)
+
+	testCases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"90s", now.Add(90 * time.Second)},
+		{"@every 2h", now.Add(2 * time.Hour)},
+		{"@hourly", time.Date(2024, 5, 3, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 5, 3, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 5, 3, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)},
+		{"30 2 1,15 * *", time.Date(2024, 5, 15, 2, 30, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Restricted day of month and day of week match either of them
+		{"0 12 31 * 1", time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)},
+		// Day field covering every day is not restricted, whatever way it is written
+		{"0 0 1-31 * 1", time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */1 * 1", time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0-6", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 1-7", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			schedule, err := scheduler.Parse(tc.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tc.next, schedule.Next(now))
+		})
+	}
+
+	// Invalid specifications are rejected
+	for _, spec := range []string{"", "-5m", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "@sometimes"} {
+		_, err := scheduler.Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSchedulerRunsJobs(t *testing.T) {
+	s := scheduler.New()
+
+	// Job running longer than its interval never overlaps with itself
+	var runs, active, overlaps int32
+	require.NoError(t, s.Add(scheduler.Job{
+		Name:       "slow",
+		Schedule:   scheduler.Every(time.Millisecond),
+		RunOnStart: true,
+		Run: func(ctx context.Context) error {
+			if atomic.AddInt32(&active, 1) > 1 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			defer atomic.AddInt32(&active, -1)
+			atomic.AddInt32(&runs, 1)
+			time.Sleep(5 * time.Millisecond)
+			return nil
+		},
+	}))
+
+	// Job without run on start waits for its first scheduled time
+	var lateRuns int32
+	require.NoError(t, s.Add(scheduler.Job{
+		Name:     "late",
+		Schedule: scheduler.Every(time.Hour),
+		Run: func(ctx context.Context) error {
+			atomic.AddInt32(&lateRuns, 1)
+			return nil
+		},
+	}))
+
+	// Panicking job does not break scheduler
+	require.NoError(t, s.Add(scheduler.Job{
+		Name:     "panicking",
+		Schedule: scheduler.Every(time.Millisecond),
+		Run: func(ctx context.Context) error {
+			panic("boom")
+		},
+	}))
+	assert.Error(t, s.Add(scheduler.Job{Name: "invalid"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Start(ctx)
+	assert.Error(t, s.Add(scheduler.Job{Name: "too late", Schedule: scheduler.Every(time.Second),
+		Run: func(ctx context.Context) error { return nil }}))
+	time.Sleep(50 * time.Millisecond)
+
+	// Cancelled context stops all jobs
+	cancel()
+	s.Wait()
+	stopped := atomic.LoadInt32(&runs)
+	time.Sleep(20 * time.Millisecond)
+
+	assert.Greater(t, stopped, int32(1))
+	assert.Equal(t, stopped, atomic.LoadInt32(&runs))
+	assert.Zero(t, atomic.LoadInt32(&overlaps))
+	assert.Zero(t, atomic.LoadInt32(&lateRuns))
+}
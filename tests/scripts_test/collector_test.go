@@ -0,0 +1,84 @@
+package scripts_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/scheduler"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingSource returns records only after release channel is closed, or fails if err is set
+type blockingSource struct {
+	release chan struct{}
+	err     error
+}
+
+func (s *blockingSource) Name() string { return "blocking" }
+
+func (s *blockingSource) Fetch(ctx context.Context) ([]scripts.Record, error) {
+	<-s.release
+	if s.err != nil {
+		return nil, s.err
+	}
+	return []scripts.Record{{"name": "Rex", "category": "Dogs"}}, nil
+}
+
+func (s *blockingSource) Map(record scripts.Record) (scripts.CollectedProduct, error) {
+	name := record["name"].(string)
+	return scripts.CollectedProduct{
+		Product:    models.Product{Name: name, Source: s.Name(), ExternalID: name},
+		Categories: []models.Category{{Name: record["category"].(string)}},
+	}, nil
+}
+
+func TestCollectorRuns(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	require.NoError(t, scripts.Migrate(db))
+
+	// Run left over by crashed process is marked as failed
+	_, err = models.StartCollectorRun(context.Background(), db, "blocking")
+	require.NoError(t, err)
+
+	source := &blockingSource{release: make(chan struct{})}
+	collector := scripts.NewCollector(db, []scripts.ScheduledSource{{Source: source, Schedule: scheduler.Every(time.Hour)}})
+	last, err := models.GetLastCollectorRun(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, models.RunFailed, last.Status)
+
+	// Second collection is refused while first one is running
+	require.NoError(t, collector.Trigger())
+	assert.True(t, collector.Running())
+	assert.ErrorIs(t, collector.Trigger(), scripts.ErrCollectionInProgress)
+	close(source.release)
+	collector.Wait()
+	assert.False(t, collector.Running())
+
+	last, err = models.GetLastCollectorRun(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, models.RunSucceeded, last.Status)
+	assert.Equal(t, 1, last.Inserted)
+	assert.NotNil(t, last.FinishedAt)
+	assert.Empty(t, last.Error)
+
+	// Failed collection is recorded with its error
+	source.err = errors.New("upstream is down")
+	require.NoError(t, collector.Trigger())
+	collector.Wait()
+
+	runs, err := models.GetCollectorRuns(context.Background(), db, 0)
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+	assert.Equal(t, models.RunFailed, runs[0].Status)
+	assert.Equal(t, "upstream is down", runs[0].Error)
+	assert.Equal(t, "blocking", runs[0].Source)
+}
@@ -0,0 +1,277 @@
+package scripts_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/httpclient"
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/scheduler"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSourceMap(t *testing.T) {
+	source, err := scripts.NewJSONSource(scripts.SourceConfig{
+		Name: "shop",
+		URL:  "http://example.com",
+		Fields: scripts.FieldMapping{
+			Name:        "title",
+			Category:    "meta.tags",
+			Description: "body",
+			Price:       "price",
+			SKU:         "id",
+		},
+		PriceScale: 100,
+	})
+	require.NoError(t, err)
+
+	// Record with nested array of categories and price in major units
+	collected, err := source.Map(scripts.Record{
+		"title": "Bread",
+		"body":  "Fresh bread",
+		"price": 1.99,
+		"id":    float64(42),
+		"meta":  map[string]interface{}{"tags": []interface{}{"Food", "Bakery"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Bread", collected.Product.Name)
+	assert.Equal(t, "Fresh bread", collected.Product.Description)
+	assert.Equal(t, int64(199), collected.Product.Price)
+	assert.Equal(t, "42", collected.Product.SKU)
+	assert.Equal(t, []models.Category{{Name: "Food"}, {Name: "Bakery"}}, collected.Categories)
+
+	// Records without name or category are rejected
+	_, err = source.Map(scripts.Record{"meta": map[string]interface{}{"tags": []interface{}{"Food"}}})
+	assert.Error(t, err)
+	_, err = source.Map(scripts.Record{"title": "Bread"})
+	assert.Error(t, err)
+}
+
+func TestCollectAndSaveProducts(t *testing.T) {
+	// Local stand-in for petstore wrapping pets in object
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": [
+			{"name": "Rex", "category": {"name": "Dogs"}},
+			{"name": "Tom", "category": {"name": "Cats"}},
+			{"name": "Nameless"}
+		]}`))
+	}))
+	defer server.Close()
+
+	_, err := scripts.NewSource(scripts.SourceConfig{Type: "json", URL: server.URL})
+	assert.Error(t, err, "json source without field mapping should be rejected")
+	source, err := scripts.NewJSONSource(scripts.SourceConfig{
+		Name:      "pets",
+		URL:       server.URL,
+		ItemsPath: "data",
+		Fields:    scripts.FieldMapping{Name: "name", Category: "category.name"},
+	})
+	require.NoError(t, err)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	require.NoError(t, scripts.Migrate(db))
+
+	// Collect products and check that valid records were saved
+	summary, err := scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "pets", Inserted: 2, Failed: 1}, summary)
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM products").Scan(&count))
+	assert.Equal(t, 2, count)
+}
+
+func TestCollectAndSaveProductsSync(t *testing.T) {
+	// Local stand-in for petstore returning current feed
+	feed := `[
+		{"id": 1, "name": "Rex", "category": {"name": "Dogs"}},
+		{"id": 2, "name": "Tom", "category": {"name": "Cats"}}
+	]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(feed))
+	}))
+	defer server.Close()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	require.NoError(t, scripts.Migrate(db))
+
+	// Product created by hand is left alone by source not configured to adopt products
+	manual := &models.Product{Name: "Tom"}
+	require.NoError(t, models.AddProduct(context.Background(), db, manual, []models.Category{{Name: "Cats"}}))
+	source, err := scripts.NewSource(scripts.SourceConfig{Type: "petstore", URL: server.URL})
+	require.NoError(t, err)
+	summary, err := scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "petstore", Inserted: 1, Failed: 1}, summary)
+	_, err = models.GetProductByExternalID(context.Background(), db, "petstore", "2")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// Source configured to adopt products takes it over instead of duplicating it
+	source, err = scripts.NewSource(scripts.SourceConfig{Type: "petstore", URL: server.URL, AdoptExisting: true})
+	require.NoError(t, err)
+	summary, err = scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "petstore", Updated: 1, Adopted: 1, Unchanged: 1}, summary)
+	adopted, err := models.GetProductByExternalID(context.Background(), db, "petstore", "2")
+	require.NoError(t, err)
+	assert.Equal(t, manual.ID, adopted.ID)
+	assert.True(t, adopted.UpdatedAt.After(manual.UpdatedAt), "adopted product should have new modification time")
+
+	// Same feed leaves products unchanged
+	summary, err = scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "petstore", Unchanged: 2}, summary)
+
+	// Renamed and recategorized product is updated in place, missing product is discontinued
+	feed = `[{"id": 1, "name": "Rex II", "category": {"name": "Puppies"}}]`
+	summary, err = scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "petstore", Updated: 1, Removed: 1}, summary)
+
+	rex, err := models.GetProductByExternalID(context.Background(), db, "petstore", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "Rex II", rex.Name)
+	assert.Nil(t, rex.DiscontinuedAt)
+	categories, err := models.GetCategoriesByProductID(context.Background(), db, int64(rex.ID))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Puppies"}, categories)
+
+	tom, err := models.GetProductByExternalID(context.Background(), db, "petstore", "2")
+	require.NoError(t, err)
+	assert.NotNil(t, tom.DiscontinuedAt)
+
+	// Record that cannot be mapped still keeps its product active
+	feed = `[{"id": 1, "name": "Rex II"}]`
+	summary, err = scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "petstore", Failed: 1}, summary)
+	rex, err = models.GetProductByExternalID(context.Background(), db, "petstore", "1")
+	require.NoError(t, err)
+	assert.Nil(t, rex.DiscontinuedAt)
+
+	// Record without identity may belong to any product, so nothing is discontinued
+	feed = `[{"category": {"name": "Dogs"}}]`
+	summary, err = scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "petstore", Failed: 1}, summary)
+	rex, err = models.GetProductByExternalID(context.Background(), db, "petstore", "1")
+	require.NoError(t, err)
+	assert.Nil(t, rex.DiscontinuedAt)
+
+	// Empty feed does not discontinue anything
+	feed = `[]`
+	summary, err = scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "petstore"}, summary)
+
+	// Product returning to feed becomes active again
+	feed = `[{"id": 2, "name": "Tom", "category": {"name": "Cats"}}]`
+	summary, err = scripts.CollectAndSaveProducts(db, source)
+	require.NoError(t, err)
+	assert.Equal(t, scripts.SyncSummary{Source: "petstore", Updated: 1, Removed: 1}, summary)
+	tom, err = models.GetProductByExternalID(context.Background(), db, "petstore", "2")
+	require.NoError(t, err)
+	assert.Nil(t, tom.DiscontinuedAt)
+}
+
+func TestLoadSources(t *testing.T) {
+	// Default configuration collects emojihub every hour
+	sources, err := scripts.LoadSources("")
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, "emojihub", sources[0].Source.Name())
+	assert.Equal(t, scheduler.Every(time.Hour), sources[0].Schedule)
+
+	// Configuration file lists sources with their own intervals
+	path := filepath.Join(t.TempDir(), "sources.json")
+	content := `[
+		{"type": "petstore", "interval": "6h", "adopt_existing": true},
+		{"type": "json", "name": "shop", "url": "http://example.com", "interval": "15m",
+		 "fields": {"name": "title", "category": "category"}},
+		{"type": "emojihub", "schedule": "30 3 * * *", "run_on_start": true}
+	]`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	sources, err = scripts.LoadSources(path)
+	require.NoError(t, err)
+	require.Len(t, sources, 3)
+	assert.Equal(t, "petstore", sources[0].Source.Name())
+	assert.Equal(t, scheduler.Every(6*time.Hour), sources[0].Schedule)
+	assert.False(t, sources[0].RunOnStart)
+	assert.True(t, sources[0].Source.(scripts.Adopter).AdoptsExisting())
+	assert.False(t, sources[1].Source.(scripts.Adopter).AdoptsExisting())
+	assert.Equal(t, "shop", sources[1].Source.Name())
+	assert.Equal(t, scheduler.Every(15*time.Minute), sources[1].Schedule)
+	assert.True(t, sources[2].RunOnStart)
+	next := sources[2].Schedule.Next(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 5, 2, 3, 30, 0, 0, time.UTC), next)
+
+	// Interval and schedule cannot be combined
+	require.NoError(t, os.WriteFile(path, []byte(`[{"type": "petstore", "interval": "1h", "schedule": "@daily"}]`), 0600))
+	_, err = scripts.LoadSources(path)
+	assert.Error(t, err)
+
+	// Unknown source type is rejected
+	require.NoError(t, os.WriteFile(path, []byte(`[{"type": "ftp"}]`), 0600))
+	_, err = scripts.LoadSources(path)
+	assert.Error(t, err)
+
+	// Sources with same name are rejected, whether both are built-in or one is named like built-in
+	require.NoError(t, os.WriteFile(path, []byte(`[{"type": "emojihub"}, {"type": "emojihub"}]`), 0600))
+	_, err = scripts.LoadSources(path)
+	assert.ErrorContains(t, err, `duplicate source name "emojihub"`)
+	require.NoError(t, os.WriteFile(path, []byte(`[{"type": "petstore"},
+		{"type": "json", "name": "petstore", "url": "http://example.com", "fields": {"name": "title", "category": "category"}}]`), 0600))
+	_, err = scripts.LoadSources(path)
+	assert.ErrorContains(t, err, `duplicate source name "petstore"`)
+}
+
+func TestEmojiHubSourceRetries(t *testing.T) {
+	// Local stand-in for emojihub failing on first request
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"name": "grinning face", "category": "smileys and people", "unicode": ["U+1F600"]},
+			{"name": "dog face", "category": "animals and nature", "unicode": ["U+1F436"]}
+		]`))
+	}))
+	defer server.Close()
+
+	scripts.SetHTTPClient(httpclient.New(httpclient.Config{
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         time.Millisecond,
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+	}))
+	defer scripts.SetHTTPClient(httpclient.New(httpclient.DefaultConfig()))
+
+	source, err := scripts.NewSource(scripts.SourceConfig{Type: "emojihub", URL: server.URL})
+	require.NoError(t, err)
+
+	records, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
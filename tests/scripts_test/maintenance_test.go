@@ -0,0 +1,73 @@
+package scripts_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/scripts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanupCollectorRuns(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	require.NoError(t, scripts.Migrate(db))
+
+	// One old finished run, one recent finished run
+	old, err := models.StartCollectorRun(context.Background(), db, "emojihub")
+	require.NoError(t, err)
+	require.NoError(t, models.FinishCollectorRun(context.Background(), db, old, nil))
+	_, err = db.Exec("UPDATE collector_runs SET started_at = ? WHERE id = ?", time.Now().UTC().Add(-48*time.Hour), old.ID)
+	require.NoError(t, err)
+	recent, err := models.StartCollectorRun(context.Background(), db, "emojihub")
+	require.NoError(t, err)
+	require.NoError(t, models.FinishCollectorRun(context.Background(), db, recent, nil))
+
+	deleted, err := scripts.CleanupCollectorRuns(context.Background(), db, 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	runs, err := models.GetCollectorRuns(context.Background(), db, 0)
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, recent.ID, runs[0].ID)
+}
+
+func TestBackupDatabase(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	require.NoError(t, scripts.Migrate(db))
+	require.NoError(t, models.AddProduct(context.Background(), db, &models.Product{Name: "Bread"}, []models.Category{{Name: "Food"}}))
+
+	// Backup is complete copy of database
+	dir := t.TempDir()
+	path, err := scripts.BackupDatabase(context.Background(), db, dir, 2)
+	require.NoError(t, err)
+
+	backup, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer backup.Close()
+	var count int
+	require.NoError(t, backup.QueryRow("SELECT COUNT(*) FROM products").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	// Only most recent backups are kept
+	for i := 0; i < 2; i++ {
+		time.Sleep(2 * time.Millisecond)
+		_, err = scripts.BackupDatabase(context.Background(), db, dir, 2)
+		require.NoError(t, err)
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
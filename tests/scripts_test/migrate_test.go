@@ -1,6 +1,7 @@
 package scripts_test
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"github.com/MaximInnopolis/ProductCatalog/scripts"
@@ -28,6 +29,259 @@ func TestMigrate(t *testing.T) {
 	}
 }
 
+func TestMigrateAddsProductColumns(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Create 'products' table in its original form with existing product
+	_, err = db.Exec("CREATE TABLE products (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO products (name) VALUES ('Bread')")
+	require.NoError(t, err)
+
+	// Run migration
+	err = scripts.Migrate(db)
+	require.NoError(t, err)
+
+	// Check that new columns were added and filled for existing product
+	var price int64
+	var currency string
+	var createdAt sql.NullTime
+	err = db.QueryRow("SELECT price, currency, created_at FROM products WHERE name = 'Bread'").Scan(&price, &currency, &createdAt)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), price)
+	assert.Equal(t, "USD", currency)
+	assert.True(t, createdAt.Valid)
+
+	// Migration must be safe to run again
+	require.NoError(t, scripts.Migrate(db))
+}
+
+func TestMigrateLegacySchemaAtomically(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	// Legacy 'users' is view, so adding its column fails after 'products' columns are added
+	_, err = db.Exec("CREATE TABLE products (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE)")
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE VIEW users AS SELECT 1 AS id")
+	require.NoError(t, err)
+	require.Error(t, scripts.Migrate(db))
+
+	// Check that 'products' table was left as it was
+	var columns int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM pragma_table_info('products')").Scan(&columns))
+	assert.Equal(t, 2, columns)
+
+	// Upgrade succeeds once cause of failure is removed
+	_, err = db.Exec("DROP VIEW users")
+	require.NoError(t, err)
+	require.NoError(t, scripts.Migrate(db))
+}
+
+func TestMigrateRemovesDanglingLinks(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	// Create schema of first version with link to category that no longer exists
+	require.NoError(t, scripts.MigrateTo(db, 1))
+	_, err = db.Exec("INSERT INTO products (name) VALUES ('Bread')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO categories (name) VALUES ('Food')")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO product_categories (product_id, category_id) VALUES (1, 1), (1, 2)")
+	require.NoError(t, err)
+
+	// Apply remaining migrations
+	require.NoError(t, scripts.Migrate(db))
+
+	// Check that only valid link is kept
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM product_categories").Scan(&count))
+	assert.Equal(t, 1, count)
+}
+
+func TestMigrateDownAndTo(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrations, err := scripts.LoadMigrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	latest := migrations[len(migrations)-1].Version
+
+	// Apply all migrations
+	require.NoError(t, scripts.Migrate(db))
+
+	// Check that every migration is reported as applied, unless SQLite lacks option it requires
+	statuses, err := scripts.GetMigrationStatus(db)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(migrations))
+	applied := []int{}
+	for _, status := range statuses {
+		assert.NotEqual(t, status.Applied, status.Skipped, "Migration %d should be either applied or skipped", status.Version)
+		if status.Applied {
+			assert.False(t, status.AppliedAt.IsZero())
+			applied = append(applied, status.Version)
+		}
+	}
+	require.NotEmpty(t, applied)
+	version, err := scripts.CurrentVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, applied[len(applied)-1], version)
+
+	// Revert all migrations
+	require.NoError(t, scripts.MigrateTo(db, 0))
+	version, err = scripts.CurrentVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, version)
+	exists, err := tableExists(db, "products")
+	require.NoError(t, err)
+	assert.False(t, exists, "Table products should be dropped")
+
+	// Apply everything again and revert last applied migration only
+	require.NoError(t, scripts.MigrateUp(db))
+	require.NoError(t, scripts.MigrateDown(db))
+	version, err = scripts.CurrentVersion(db)
+	require.NoError(t, err)
+	if len(applied) > 1 {
+		assert.Equal(t, applied[len(applied)-2], version)
+	} else {
+		assert.Equal(t, 0, version)
+	}
+
+	// Unknown version is rejected
+	assert.Error(t, scripts.MigrateTo(db, latest+1))
+}
+
+func TestUniqueUsernamesMigration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	migrations, err := scripts.LoadMigrations()
+	require.NoError(t, err)
+	var unique *scripts.Migration
+	for i := range migrations {
+		if migrations[i].Name == "unique_usernames" {
+			unique = &migrations[i]
+		}
+	}
+	require.NotNil(t, unique)
+
+	// Users registered twice before usernames were unique
+	require.NoError(t, scripts.MigrateTo(db, unique.Version-1))
+	_, err = db.Exec(`INSERT INTO users (username, password, role) VALUES
+		('alice', 'first', 'admin'), ('alice', 'second', 'viewer'), ('bob', 'only', 'viewer')`)
+	require.NoError(t, err)
+
+	// User who registered first keeps username
+	require.NoError(t, scripts.MigrateTo(db, unique.Version))
+	var password string
+	require.NoError(t, db.QueryRow("SELECT password FROM users WHERE username = 'alice'").Scan(&password))
+	assert.Equal(t, "first", password)
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count))
+	assert.Equal(t, 2, count)
+
+	// Same username cannot be registered again
+	_, err = db.Exec("INSERT INTO users (username, password) VALUES ('bob', 'again')")
+	assert.Error(t, err)
+}
+
+func TestPendingMigrations(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	ctx := context.Background()
+
+	// Empty database has every supported migration pending and is not changed by check
+	current, pending, err := scripts.PendingMigrations(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, 0, current)
+	require.NotEmpty(t, pending)
+	assert.Equal(t, 1, pending[0].Version)
+	exists, err := tableExists(db, "schema_migrations")
+	require.NoError(t, err)
+	assert.False(t, exists, "Check should not create 'schema_migrations' table")
+
+	// Migrated database has nothing pending
+	require.NoError(t, scripts.Migrate(db))
+	current, pending, err = scripts.PendingMigrations(ctx, db)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	version, err := scripts.CurrentVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, version, current)
+
+	// Reverted migration is pending again
+	require.NoError(t, scripts.MigrateDown(db))
+	current, pending, err = scripts.PendingMigrations(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, version, pending[0].Version)
+	assert.Less(t, current, version)
+
+	// Cancelled context is respected
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = scripts.PendingMigrations(cancelled, db)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSearchIndexMigration(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	// Create schema without search index and add product
+	migrations, err := scripts.LoadMigrations()
+	require.NoError(t, err)
+	var index *scripts.Migration
+	for i := range migrations {
+		if migrations[i].Name == "products_fts" {
+			index = &migrations[i]
+		}
+	}
+	require.NotNil(t, index)
+	assert.Equal(t, "ENABLE_FTS5", index.Requires)
+	require.NoError(t, scripts.MigrateTo(db, index.Version-1))
+	_, err = db.Exec("INSERT INTO products (name, description) VALUES ('Bread', 'Fresh rye bread')")
+	require.NoError(t, err)
+
+	// Index is created only if SQLite supports FTS5, then existing products are indexed
+	require.NoError(t, scripts.MigrateTo(db, index.Version))
+	var fts5 bool
+	require.NoError(t, db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5))
+	exists, err := tableExists(db, "products_fts")
+	require.NoError(t, err)
+	assert.Equal(t, fts5, exists)
+	if !fts5 {
+		return
+	}
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM products_fts WHERE products_fts MATCH 'rye'").Scan(&count))
+	assert.Equal(t, 1, count)
+
+	// Reverting migration removes index and its triggers, so products can still be changed
+	require.NoError(t, scripts.MigrateTo(db, index.Version-1))
+	exists, err = tableExists(db, "products_fts")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	_, err = db.Exec("UPDATE products SET description = 'Dark rye bread'")
+	assert.NoError(t, err)
+}
+
 func tableExists(db *sql.DB, tableName string) (bool, error) {
 	query := "SELECT name FROM sqlite_master WHERE type='table' AND name=?"
 	var name string
@@ -0,0 +1,161 @@
+package validation_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/MaximInnopolis/ProductCatalog/internal/models"
+	"github.com/MaximInnopolis/ProductCatalog/internal/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tag struct {
+	Name string `json:"name" validate:"trim,nfc,required,max=10,charset=name"`
+}
+
+type item struct {
+	Name     string   `json:"name" validate:"trim,nfc,required,min=2,max=10,charset=name"`
+	Note     string   `json:"note" validate:"trim,max=20,charset=text"`
+	Code     string   `json:"code" validate:"trim,min=3,max=3,charset=upper"`
+	Login    string   `json:"login" validate:"charset=identifier"`
+	Count    int      `json:"count" validate:"min=0,max=5"`
+	Kind     string   `json:"kind" validate:"oneof=small large"`
+	Tags     []tag    `json:"tags" validate:"required,max=2"`
+	Untagged string   `json:"untagged"`
+	Aliases  []string `json:"aliases" validate:"max=1"`
+}
+
+// violations returns violations of error, failing test if error does not carry them
+func violations(t *testing.T, err error) map[string]string {
+	t.Helper()
+	var domainErr *models.Error
+	require.True(t, errors.As(err, &domainErr), "expected domain error, got %v", err)
+	assert.ErrorIs(t, err, models.ErrValidation)
+	assert.Equal(t, models.CodeValidationFailed, domainErr.Code)
+
+	codes := make(map[string]string)
+	for _, violation := range domainErr.Violations {
+		assert.NotEmpty(t, violation.Message)
+		codes[violation.Field] = violation.Code
+	}
+	return codes
+}
+
+func TestValidateNormalizes(t *testing.T) {
+	value := item{
+		Name: "  Café ",
+		Code: " USD ",
+		Tags: []tag{{Name: " Dogs "}},
+	}
+	require.NoError(t, validation.Validate(&value))
+
+	assert.Equal(t, "Café", value.Name)
+	assert.Equal(t, "USD", value.Code)
+	assert.Equal(t, "Dogs", value.Tags[0].Name)
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	value := item{
+		Name:    "   ",
+		Note:    "line\x00break",
+		Code:    "usd",
+		Login:   "bad login",
+		Count:   7,
+		Kind:    "medium",
+		Tags:    []tag{{Name: "ok"}, {Name: strings.Repeat("x", 11)}, {Name: "three"}},
+		Aliases: []string{"a", "b"},
+	}
+	codes := violations(t, validation.Validate(&value))
+
+	assert.Equal(t, map[string]string{
+		"name":         "required",
+		"note":         "invalid_characters",
+		"code":         "invalid_characters",
+		"login":        "invalid_characters",
+		"count":        "too_large",
+		"kind":         "not_allowed",
+		"tags":         "too_long",
+		"tags[1].name": "too_long",
+		"aliases":      "too_long",
+	}, codes)
+}
+
+func TestValidateOptionalAndRequired(t *testing.T) {
+	// Optional fields may be omitted, while required ones may not
+	codes := violations(t, validation.Validate(&item{}))
+	assert.Equal(t, map[string]string{"name": "required", "tags": "required"}, codes)
+
+	// Lengths are counted in characters, not bytes
+	value := item{Name: "Пёс🐕‍🦺", Count: -1, Tags: []tag{{Name: "a"}}}
+	codes = violations(t, validation.Validate(&value))
+	assert.Equal(t, map[string]string{"count": "too_small"}, codes)
+
+	value = item{Name: "x", Tags: []tag{{Name: "a"}}}
+	codes = violations(t, validation.Validate(&value))
+	assert.Equal(t, map[string]string{"name": "too_short"}, codes)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	decode := func(body string) error {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		var value item
+		return validation.DecodeJSON(req, &value)
+	}
+
+	assert.NoError(t, decode(`{"name":"Rex","tags":[{"name":"Dogs"}]}`))
+	assert.NoError(t, decode(`{"name":"Rex","tags":[{"name":"Dogs"}]}`+"\n"))
+
+	testCases := []struct {
+		name string
+		body string
+		kind error
+		code string
+	}{
+		{"empty body", ``, models.ErrMalformed, models.CodeMalformedBody},
+		{"invalid JSON", `{"name":}`, models.ErrMalformed, models.CodeMalformedBody},
+		{"incomplete JSON", `{"name":"Rex"`, models.ErrMalformed, models.CodeMalformedBody},
+		{"wrong type", `{"name":42}`, models.ErrMalformed, models.CodeMalformedBody},
+		{"unknown field", `{"name":"Rex","tags":[{"name":"Dogs"}],"id":1}`, models.ErrMalformed, models.CodeMalformedBody},
+		{"trailing data", `{"name":"Rex","tags":[{"name":"Dogs"}]} {}`, models.ErrMalformed, models.CodeMalformedBody},
+		{"trailing garbage", `{"name":"Rex","tags":[{"name":"Dogs"}]}x`, models.ErrMalformed, models.CodeMalformedBody},
+		{"invalid value", `{"name":"","tags":[{"name":"Dogs"}]}`, models.ErrValidation, models.CodeValidationFailed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := decode(tc.body)
+			var domainErr *models.Error
+			require.True(t, errors.As(err, &domainErr), "expected domain error, got %v", err)
+			assert.ErrorIs(t, err, tc.kind)
+			assert.Equal(t, tc.code, domainErr.Code)
+		})
+	}
+}
+
+func TestUnmarshalExactFieldNames(t *testing.T) {
+	var value item
+	assert.NoError(t, validation.Unmarshal([]byte(`{"name":"Rex","tags":[{"name":"Dogs"}]}`), &value))
+
+	// Keys differing from field names only by case are rejected, however deep they are
+	for _, body := range []string{
+		`{"Name":"Rex","tags":[{"name":"Dogs"}]}`,
+		`{"name":"Rex","tags":[{"NAME":"Dogs"}]}`,
+	} {
+		var value item
+		err := validation.Unmarshal([]byte(body), &value)
+		require.ErrorIs(t, err, models.ErrMalformed, body)
+	}
+}
+
+func TestDecodeJSONTooLarge(t *testing.T) {
+	body := `{"name":"Rex","note":"` + strings.Repeat("x", 100) + `","tags":[{"name":"Dogs"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 32)
+
+	var value item
+	err := validation.DecodeJSON(req, &value)
+	assert.ErrorIs(t, err, models.ErrTooLarge)
+}